@@ -0,0 +1,132 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PublicURL is the base URL of the web frontend, used to build permalinks
+func PublicURL() string {
+	if url := os.Getenv("PUBLIC_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:3000"
+}
+
+// TrashRetention is how long deleted posts can be restored before they are purged
+func TrashRetention() time.Duration {
+	return durationFromEnv("POST_TRASH_RETENTION", 30*24*time.Hour)
+}
+
+// ReactionTypes lists the accepted reaction types: "like" plus the emoji set
+// configured as a comma-separated REACTION_EMOJIS list.
+func ReactionTypes() []string {
+	emojis := os.Getenv("REACTION_EMOJIS")
+	if emojis == "" {
+		emojis = "❤️,😂,😮,😢,🎉"
+	}
+
+	types := []string{"like"}
+	for _, e := range strings.Split(emojis, ",") {
+		if e = strings.TrimSpace(e); e != "" && !strings.ContainsAny(e, ".$") {
+			types = append(types, e)
+		}
+	}
+	return types
+}
+
+// APIURL is the public base URL of this API, used to build media URLs
+func APIURL() string {
+	if url := os.Getenv("API_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// MediaMaxBytes is the largest accepted media upload
+func MediaMaxBytes() int64 {
+	return int64(intFromEnv("MEDIA_MAX_BYTES", 10<<20))
+}
+
+// MaxPinnedPosts is how many posts a user may pin to their profile
+func MaxPinnedPosts() int {
+	return intFromEnv("MAX_PINNED_POSTS", 3)
+}
+
+// IdempotencyWindow is how long the response to a request with an
+// Idempotency-Key is kept for replay
+func IdempotencyWindow() time.Duration {
+	return durationFromEnv("IDEMPOTENCY_WINDOW", 24*time.Hour)
+}
+
+// ImportMaxBytes is the largest accepted archive for importing posts
+func ImportMaxBytes() int64 {
+	return int64(intFromEnv("IMPORT_MAX_BYTES", 200<<20))
+}
+
+// StorageConfig selects and configures the media storage backend
+type StorageConfig struct {
+	Backend   string // "local" or "s3"
+	Dir       string
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// Storage reads the media storage configuration
+func Storage() StorageConfig {
+	cfg := StorageConfig{
+		Backend:   os.Getenv("STORAGE_BACKEND"),
+		Dir:       os.Getenv("MEDIA_DIR"),
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		Region:    os.Getenv("S3_REGION"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = "local"
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "./uploads"
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg
+}
+
+// durationFromEnv reads a Go duration (e.g. "72h") from the environment
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// intFromEnv reads a positive integer from the environment
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
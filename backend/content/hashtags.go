@@ -0,0 +1,47 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MaxTagLength is the longest hashtag that is recognised, without the leading #
+const MaxTagLength = 64
+
+// hashtagPattern matches a # that starts a word, followed by letters, digits, marks or underscores
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{M}\p{N}_]+)`)
+
+// ExtractHashtags returns the distinct normalized hashtags in text, in order of first use
+func ExtractHashtags(text string) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, m := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag, ok := NormalizeTag(m[1])
+		if !ok || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeTag lowercases a tag and strips a leading #. Tags made only of
+// digits or underscores (e.g. "#1") and overly long tags are rejected.
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || len([]rune(tag)) > MaxTagLength {
+		return "", false
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r) && r != '_' {
+			return "", false
+		}
+	}
+	if strings.IndexFunc(tag, unicode.IsLetter) < 0 {
+		return "", false
+	}
+	return tag, true
+}
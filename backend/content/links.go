@@ -0,0 +1,56 @@
+package content
+
+import (
+	"regexp"
+	"strings"
+)
+
+// urlPattern matches http and https URLs up to the next whitespace or angle bracket
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// ExtractURLs returns the distinct http(s) URLs in text, in order of appearance.
+// Trailing punctuation that usually ends a sentence is not part of the URL.
+func ExtractURLs(text string) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, loc := range FindURLs(text) {
+		u := text[loc[0]:loc[1]]
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// FindURLs returns the byte offsets [start, end) of every http(s) URL in text,
+// with trailing sentence punctuation left out as in ExtractURLs
+func FindURLs(text string) [][]int {
+	locs := urlPattern.FindAllStringIndex(text, -1)
+	for _, loc := range locs {
+		loc[1] = loc[0] + len(trimURL(text[loc[0]:loc[1]]))
+	}
+	return locs
+}
+
+// trimURL strips trailing punctuation, keeping a closing bracket that pairs with one in the URL
+func trimURL(u string) string {
+	// Counted once and kept up to date, as a URL can end in a long run of brackets
+	parens := strings.Count(u, "(") - strings.Count(u, ")")
+	brackets := strings.Count(u, "[") - strings.Count(u, "]")
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,:;!?'*", last) >= 0:
+		case last == ')' && parens < 0:
+			parens++
+		case last == ']' && brackets < 0:
+			brackets++
+		default:
+			return u
+		}
+		u = u[:len(u)-1]
+	}
+	return u
+}
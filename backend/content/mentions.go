@@ -0,0 +1,33 @@
+package content
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// mentionPattern matches @username where the @ does not follow a word character,
+// so e-mail addresses are not picked up
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]{3,30})\b`)
+
+// MentionToken is an @username found in text. Start and End are offsets in
+// Unicode code points, covering the whole token including the @.
+type MentionToken struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// ExtractMentions returns every @username token in text, in order
+func ExtractMentions(text string) []MentionToken {
+	var tokens []MentionToken
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		// m[2]:m[3] is the username; the @ sits just before it
+		at := m[2] - 1
+		tokens = append(tokens, MentionToken{
+			Username: text[m[2]:m[3]],
+			Start:    utf8.RuneCountInString(text[:at]),
+			End:      utf8.RuneCountInString(text[:m[3]]),
+		})
+	}
+	return tokens
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// Publish appends events to the events collection, which acts as an outbox
+// for consumers such as a notification service
+func Publish(ctx context.Context, db *mongo.Database, evts ...models.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(evts))
+	for _, e := range evts {
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = now
+		}
+		docs = append(docs, e)
+	}
+
+	_, err := db.Collection("events").InsertMany(ctx, docs)
+	return err
+}
+
+// ClaimNext atomically takes the oldest unclaimed event of the given type.
+// It returns mongo.ErrNoDocuments when there is nothing to do.
+func ClaimNext(ctx context.Context, db *mongo.Database, eventType string) (models.Event, error) {
+	var event models.Event
+	err := db.Collection("events").FindOneAndUpdate(
+		ctx,
+		bson.M{"type": eventType, "claimed_at": nil},
+		bson.M{"$set": bson.M{"claimed_at": time.Now()}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&event)
+	return event, err
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+type AdminHandler struct {
+	db *mongo.Database
+}
+
+func NewAdminHandler(db *mongo.Database) *AdminHandler {
+	return &AdminHandler{db: db}
+}
+
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var input models.SuspendUserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if input.Until != nil && !input.Until.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Suspension end must be in the future"})
+		return
+	}
+
+	// Get user ID from context
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	if adminID.(primitive.ObjectID) == targetID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot suspend yourself"})
+		return
+	}
+
+	suspension := models.Suspension{
+		Reason:   input.Reason,
+		Until:    input.Until,
+		IssuedBy: adminID.(primitive.ObjectID),
+		IssuedAt: now,
+	}
+
+	// Admins cannot be suspended through the API
+	result := h.db.Collection("users").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": targetID, "role": bson.M{"$ne": models.RoleAdmin}},
+		bson.M{"$set": bson.M{"suspension": suspension, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var user models.User
+	if err := result.Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error suspending user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+
+	log.Printf("User %s suspended by %s", targetID.Hex(), adminID.(primitive.ObjectID).Hex())
+	if err := h.hidePosts(context.Background(), targetID); err != nil {
+		log.Printf("Error hiding posts of user %s, the sync job will retry: %v", targetID.Hex(), err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":         user.ID,
+			"username":   user.Username,
+			"suspension": user.Suspension,
+		},
+	})
+}
+
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	result, err := h.db.Collection("users").UpdateOne(
+		context.Background(),
+		bson.M{"_id": targetID},
+		bson.M{
+			"$unset": bson.M{"suspension": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		log.Printf("Error unsuspending user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsuspend user"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := h.releasePosts(context.Background(), targetID); err != nil {
+		log.Printf("Error showing posts of user %s, the sync job will retry: %v", targetID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unsuspended"})
+}
+
+// SyncSuspendedPosts brings the author_suspended flag of posts in line with
+// the suspensions of their authors. It hides the posts of suspended users
+// whose posts are not hidden yet, which catches suspensions from before the
+// flag existed and failed updates, and shows those of users whose suspension
+// has ended, including suspensions that simply ran out.
+func (h *AdminHandler) SyncSuspendedPosts(ctx context.Context) error {
+	now := time.Now()
+	cursor, err := h.db.Collection("users").Find(ctx,
+		bson.M{"$or": []bson.M{
+			{"posts_suspended": bson.M{"$ne": true}, "suspension": bson.M{"$exists": true}, "$or": []bson.M{
+				{"suspension.until": nil},
+				{"suspension.until": bson.M{"$gt": now}},
+			}},
+			{"posts_suspended": true, "$or": []bson.M{
+				{"suspension": bson.M{"$exists": false}},
+				{"suspension.until": bson.M{"$lte": now}},
+			}},
+		}},
+		options.Find().SetProjection(bson.M{"suspension": 1, "posts_suspended": 1}))
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.Suspension.Active(now) {
+			err = h.hidePosts(ctx, user.ID)
+		} else {
+			err = h.releasePosts(ctx, user.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hidePosts flags the posts of a suspended user, then records on the user that
+// they are flagged, so that a failure is retried by SyncSuspendedPosts
+func (h *AdminHandler) hidePosts(ctx context.Context, userID primitive.ObjectID) error {
+	if err := setAuthorSuspended(ctx, h.db, userID, true); err != nil {
+		return err
+	}
+	_, err := h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"posts_suspended": true}})
+	return err
+}
+
+// releasePosts clears the flag from the posts of a user whose suspension has
+// ended. If the user was suspended again in the meantime, the posts are
+// flagged once more.
+func (h *AdminHandler) releasePosts(ctx context.Context, userID primitive.ObjectID) error {
+	if err := setAuthorSuspended(ctx, h.db, userID, false); err != nil {
+		return err
+	}
+
+	result, err := h.db.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": userID, "$or": []bson.M{
+			{"suspension": bson.M{"$exists": false}},
+			{"suspension.until": bson.M{"$lte": time.Now()}},
+		}},
+		bson.M{"$unset": bson.M{"posts_suspended": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return setAuthorSuspended(ctx, h.db, userID, true)
+	}
+	return nil
+}
+
+// setAuthorSuspended flags or unflags all posts of a user
+func setAuthorSuspended(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, suspended bool) error {
+	filter := bson.M{"user_id": userID, "author_suspended": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"author_suspended": true}}
+	if !suspended {
+		filter["author_suspended"] = true
+		update = bson.M{"$unset": bson.M{"author_suspended": ""}}
+	}
+	_, err := db.Collection("posts").UpdateMany(ctx, filter, update)
+	return err
+}
@@ -176,6 +176,13 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 		return
 	}
 
+	// Refuse to issue a token to suspended accounts
+	if user.IsSuspended() {
+		log.Printf("Signin refused for suspended user: %s", user.ID.Hex())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended", "suspension": user.Suspension})
+		return
+	}
+
 	// Generate JWT token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": user.ID.Hex(),
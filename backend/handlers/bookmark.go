@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// Bookmark saves a post for the caller, optionally in one of their folders.
+// Bookmarking an already saved post moves it to the given folder.
+func (h *PostHandler) Bookmark(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// The body is optional
+	var input models.BookmarkInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+
+	// Bookmarking a repost saves the original
+	post, err := h.findOriginal(ctx, postID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	visible, err := canView(ctx, h.db, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	update := bson.M{
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+		"$unset":       bson.M{"folder_id": ""},
+	}
+	if input.FolderID != "" {
+		folderID, err := primitive.ObjectIDFromHex(input.FolderID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+			return
+		}
+		err = h.db.Collection("bookmark_folders").FindOne(ctx, bson.M{"_id": folderID, "user_id": userID}).Err()
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folder"})
+			return
+		}
+		update = bson.M{
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+			"$set":         bson.M{"folder_id": folderID},
+		}
+	}
+
+	var bookmark models.Bookmark
+	err = h.db.Collection("bookmarks").FindOneAndUpdate(
+		ctx,
+		bson.M{"user_id": userID, "post_id": post.ID},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&bookmark)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bookmark"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookmark)
+}
+
+// Unbookmark removes a post from the caller's bookmarks. Removing a missing bookmark is a no-op.
+func (h *PostHandler) Unbookmark(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	_, err = h.db.Collection("bookmarks").DeleteOne(context.Background(), bson.M{"user_id": userID, "post_id": postID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove bookmark"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bookmark removed"})
+}
+
+// GetBookmarks lists the caller's bookmarks, most recently saved first and
+// paginated like the feed. folder_id limits the list to one folder, or to
+// unfiled bookmarks when it is "none".
+func (h *PostHandler) GetBookmarks(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{"user_id": userID}
+	switch folder := c.Query("folder_id"); folder {
+	case "":
+	case "none":
+		filter["folder_id"] = nil
+	default:
+		folderID, err := primitive.ObjectIDFromHex(folder)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+			return
+		}
+		filter["folder_id"] = folderID
+	}
+
+	ctx := context.Background()
+	bookmarks, info, err := findPage(ctx, h.db.Collection("bookmarks"), filter, page, bookmarkCursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarks"})
+		return
+	}
+
+	// Posts the caller can no longer see are shown as unavailable
+	visible, err := h.publicPostsFilter(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarks"})
+		return
+	}
+	ids := make([]primitive.ObjectID, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		ids = append(ids, b.PostID)
+	}
+	visible["_id"] = bson.M{"$in": ids}
+
+	cursor, err := h.db.Collection("posts").Find(ctx, visible)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarks"})
+		return
+	}
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode bookmarks"})
+		return
+	}
+	if err := h.prepare(ctx, c, postPtrs(posts)...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarks"})
+		return
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Post, len(posts))
+	for i := range posts {
+		byID[posts[i].ID] = &posts[i]
+	}
+	for i, b := range bookmarks {
+		if p, ok := byID[b.PostID]; ok {
+			bookmarks[i].Post = p
+		} else {
+			bookmarks[i].Post = &models.Post{ID: b.PostID, Unavailable: true}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookmarks": bookmarks, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
+}
+
+// GetBookmarkFolders lists the caller's folders by name
+func (h *PostHandler) GetBookmarkFolders(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := h.db.Collection("bookmark_folders").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch folders"})
+		return
+	}
+
+	folders := []models.BookmarkFolder{}
+	if err := cursor.All(ctx, &folders); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode folders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+func (h *PostHandler) CreateBookmarkFolder(c *gin.Context) {
+	var input models.BookmarkFolderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folder name is required"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	folder := models.BookmarkFolder{
+		UserID:    userID.(primitive.ObjectID),
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	result, err := h.db.Collection("bookmark_folders").InsertOne(context.Background(), folder)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a folder with this name"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create folder"})
+		return
+	}
+	folder.ID = result.InsertedID.(primitive.ObjectID)
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (h *PostHandler) RenameBookmarkFolder(c *gin.Context) {
+	folderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var input models.BookmarkFolderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folder name is required"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var folder models.BookmarkFolder
+	err = h.db.Collection("bookmark_folders").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": folderID, "user_id": userID},
+		bson.M{"$set": bson.M{"name": name}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&folder)
+	if err != nil {
+		switch {
+		case err == mongo.ErrNoDocuments:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		case mongo.IsDuplicateKeyError(err):
+			c.JSON(http.StatusConflict, gin.H{"error": "You already have a folder with this name"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename folder"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteBookmarkFolder removes a folder. Its bookmarks are kept, unfiled.
+func (h *PostHandler) DeleteBookmarkFolder(c *gin.Context) {
+	folderID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	result, err := h.db.Collection("bookmark_folders").DeleteOne(ctx, bson.M{"_id": folderID, "user_id": userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete folder"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+
+	_, err = h.db.Collection("bookmarks").UpdateMany(ctx,
+		bson.M{"user_id": userID, "folder_id": folderID},
+		bson.M{"$unset": bson.M{"folder_id": ""}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfile bookmarks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted"})
+}
+
+// viewerBookmarks returns which of the given posts userID has bookmarked
+func viewerBookmarks(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	opts := options.Find().SetProjection(bson.M{"post_id": 1})
+	cursor, err := db.Collection("bookmarks").Find(ctx, bson.M{"user_id": userID, "post_id": bson.M{"$in": postIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookmarks []models.Bookmark
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		return nil, err
+	}
+
+	saved := make(map[primitive.ObjectID]bool, len(bookmarks))
+	for _, b := range bookmarks {
+		saved[b.PostID] = true
+	}
+	return saved, nil
+}
+
+// bookmarkCursor is the pagination key of a bookmark
+func bookmarkCursor(b models.Bookmark) pageCursor {
+	return pageCursor{CreatedAt: b.CreatedAt, ID: b.ID}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+type FollowHandler struct {
+	db *mongo.Database
+}
+
+func NewFollowHandler(db *mongo.Database) *FollowHandler {
+	return &FollowHandler{db: db}
+}
+
+// Follow makes the caller follow a user. Following someone twice is a no-op.
+func (h *FollowHandler) Follow(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	followerID := userID.(primitive.ObjectID)
+
+	ctx := context.Background()
+	target, ok := h.findUser(ctx, c)
+	if !ok {
+		return
+	}
+	if target.ID == followerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	follow := models.Follow{
+		FollowerID: followerID,
+		FolloweeID: target.ID,
+		CreatedAt:  time.Now(),
+	}
+	_, err := h.db.Collection("follows").InsertOne(ctx, follow)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+
+	// Only count the follow if this request created it
+	if err == nil {
+		h.adjustCounts(ctx, followerID, target.ID, 1)
+	}
+
+	h.respond(ctx, c, followerID, target.ID)
+}
+
+// Unfollow stops the caller following a user. Unfollowing someone the caller
+// does not follow is a no-op.
+func (h *FollowHandler) Unfollow(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	followerID := userID.(primitive.ObjectID)
+
+	ctx := context.Background()
+
+	// Suspended users can still be unfollowed
+	var target models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"username": c.Param("username")}).Decode(&target); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	result, err := h.db.Collection("follows").DeleteOne(ctx, bson.M{"follower_id": followerID, "followee_id": target.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+	if result.DeletedCount == 1 {
+		h.adjustCounts(ctx, followerID, target.ID, -1)
+	}
+
+	h.respond(ctx, c, followerID, target.ID)
+}
+
+// GetFollowers lists the users following a user, most recent first and
+// paginated like the feed
+func (h *FollowHandler) GetFollowers(c *gin.Context) {
+	h.listFollows(c, "followee_id", "follower_id")
+}
+
+// GetFollowing lists the users a user follows, most recent first and
+// paginated like the feed
+func (h *FollowHandler) GetFollowing(c *gin.Context) {
+	h.listFollows(c, "follower_id", "followee_id")
+}
+
+// listFollows pages through the follows whose by field is the requested user
+// and lists the users in their other field
+func (h *FollowHandler) listFollows(c *gin.Context, by, other string) {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	user, ok := h.findUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	follows, info, err := findPage(ctx, h.db.Collection("follows"), bson.M{by: user.ID}, page, followCursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch follows"})
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, f := range follows {
+		if other == "follower_id" {
+			ids = append(ids, f.FollowerID)
+		} else {
+			ids = append(ids, f.FolloweeID)
+		}
+	}
+
+	cursor, err := h.db.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	var found []models.User
+	if err := cursor.All(ctx, &found); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	byID := make(map[primitive.ObjectID]models.User, len(found))
+	for _, u := range found {
+		byID[u.ID] = u
+	}
+
+	var relationships map[primitive.ObjectID]models.Relationship
+	viewerID, signedIn := c.Get("user_id")
+	if signedIn {
+		relationships, err = viewerRelationships(ctx, h.db, viewerID.(primitive.ObjectID), ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch follows"})
+			return
+		}
+	}
+
+	// Suspended users are left out, as their posts are
+	moderator := models.IsModeratorRole(c.GetString("user_role"))
+	users := []models.FollowListItem{}
+	for i, id := range ids {
+		u, ok := byID[id]
+		if !ok || (u.IsSuspended() && !moderator) {
+			continue
+		}
+		item := models.FollowListItem{
+			ID:         u.ID,
+			Name:       u.Name,
+			Username:   u.Username,
+			FollowedAt: follows[i].CreatedAt,
+		}
+		if signedIn && id != viewerID.(primitive.ObjectID) {
+			rel := relationships[id]
+			item.Relationship = &rel
+		}
+		users = append(users, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"next_cursor": info.NextCursor,
+		"prev_cursor": info.PrevCursor,
+	})
+}
+
+// findUser loads the user named in the URL. Suspended users are only found
+// by moderators.
+func (h *FollowHandler) findUser(ctx context.Context, c *gin.Context) (models.User, bool) {
+	var user models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"username": c.Param("username")}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return user, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return user, false
+	}
+	if user.IsSuspended() && !models.IsModeratorRole(c.GetString("user_role")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// adjustCounts keeps the follower and following counts of both users in step
+// with the follows collection. The change times keep a reconciliation run in
+// progress from overwriting them with counts read before.
+func (h *FollowHandler) adjustCounts(ctx context.Context, followerID, followeeID primitive.ObjectID, delta int) {
+	users := h.db.Collection("users")
+	now := time.Now()
+	_, err := users.UpdateOne(ctx, bson.M{"_id": followerID}, bson.M{
+		"$inc": bson.M{"following_count": delta},
+		"$set": bson.M{"following_changed_at": now},
+	})
+	if err != nil {
+		log.Printf("Error updating following_count of user %s: %v", followerID.Hex(), err)
+	}
+	_, err = users.UpdateOne(ctx, bson.M{"_id": followeeID}, bson.M{
+		"$inc": bson.M{"followers_count": delta},
+		"$set": bson.M{"followers_changed_at": now},
+	})
+	if err != nil {
+		log.Printf("Error updating followers_count of user %s: %v", followeeID.Hex(), err)
+	}
+}
+
+// respond returns the caller's relationship with the user and the user's current counts
+func (h *FollowHandler) respond(ctx context.Context, c *gin.Context, viewerID, userID primitive.ObjectID) {
+	relationships, err := viewerRelationships(ctx, h.db, viewerID, []primitive.ObjectID{userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch follows"})
+		return
+	}
+
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"followers_count": 1, "following_count": 1})
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"relationship":    relationships[userID],
+		"followers_count": user.FollowersCount,
+		"following_count": user.FollowingCount,
+	})
+}
+
+// viewerRelationships returns how viewerID and each of userIDs follow each other
+func viewerRelationships(ctx context.Context, db *mongo.Database, viewerID primitive.ObjectID, userIDs []primitive.ObjectID) (map[primitive.ObjectID]models.Relationship, error) {
+	relationships := make(map[primitive.ObjectID]models.Relationship, len(userIDs))
+	if len(userIDs) == 0 {
+		return relationships, nil
+	}
+
+	cursor, err := db.Collection("follows").Find(ctx, bson.M{"$or": []bson.M{
+		{"follower_id": viewerID, "followee_id": bson.M{"$in": userIDs}},
+		{"follower_id": bson.M{"$in": userIDs}, "followee_id": viewerID},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var follows []models.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	for _, f := range follows {
+		if f.FollowerID == viewerID {
+			rel := relationships[f.FolloweeID]
+			rel.Following = true
+			relationships[f.FolloweeID] = rel
+		}
+		if f.FolloweeID == viewerID {
+			rel := relationships[f.FollowerID]
+			rel.FollowedBy = true
+			relationships[f.FollowerID] = rel
+		}
+	}
+	for id, rel := range relationships {
+		rel.Mutual = rel.Following && rel.FollowedBy
+		relationships[id] = rel
+	}
+	return relationships, nil
+}
+
+// followCursor is the pagination key of a follow
+func followCursor(f models.Follow) pageCursor {
+	return pageCursor{CreatedAt: f.CreatedAt, ID: f.ID}
+}
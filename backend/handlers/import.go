@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/config"
+	"unleashed-space/importer"
+	"unleashed-space/models"
+	"unleashed-space/storage"
+)
+
+type ImportHandler struct {
+	db    *mongo.Database
+	store storage.Storage
+}
+
+func NewImportHandler(db *mongo.Database, store storage.Storage) *ImportHandler {
+	return &ImportHandler{db: db, store: store}
+}
+
+// CreateImport accepts a multipart "file" archive and its "source" (mastodon
+// or twitter). The archive is checked, then imported in the background; the
+// returned job reports the progress.
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	maxBytes := config.ImportMaxBytes()
+	tooLarge := gin.H{"error": "Archive is too large, the limit is " + strconv.FormatInt(maxBytes, 10) + " bytes"}
+
+	// Leave some room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An archive file is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read archive"})
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	// Reject unreadable archives now rather than in the background
+	source := c.PostForm("source")
+	archive, err := importer.Parse(source, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	id := primitive.NewObjectID()
+	job := models.ImportJob{
+		ID:         id,
+		UserID:     userID.(primitive.ObjectID),
+		Source:     source,
+		Status:     models.ImportPending,
+		ArchiveKey: "import_" + id.Hex() + ".zip",
+		Total:      len(archive.Entries),
+		Skipped:    archive.Skipped,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := h.store.Put(ctx, job.ArchiveKey, data, "application/zip"); err != nil {
+		log.Printf("Error storing import archive %s: %v", job.ArchiveKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store archive"})
+		return
+	}
+	if _, err := h.db.Collection("import_jobs").InsertOne(ctx, job); err != nil {
+		if err := h.store.Delete(ctx, job.ArchiveKey); err != nil {
+			log.Printf("Error deleting import archive %s: %v", job.ArchiveKey, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImports lists the caller's imports, newest first
+func (h *ImportHandler) GetImports(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(20)
+	cursor, err := h.db.Collection("import_jobs").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch imports"})
+		return
+	}
+
+	jobs := []models.ImportJob{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports": jobs})
+}
+
+// GetImport reports the progress of one of the caller's imports
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	jobID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var job models.ImportJob
+	err = h.db.Collection("import_jobs").FindOne(context.Background(), bson.M{"_id": jobID, "user_id": userID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RollbackImport removes every post created by a finished import. The
+// removal runs in the background, like the import itself.
+func (h *ImportHandler) RollbackImport(c *gin.Context) {
+	jobID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	imports := h.db.Collection("import_jobs")
+
+	var job models.ImportJob
+	err = imports.FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":     jobID,
+			"user_id": userID,
+			"status":  bson.M{"$in": []string{models.ImportCompleted, models.ImportFailed}},
+		},
+		bson.M{"$set": bson.M{"status": models.ImportRollbackPending, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == nil {
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back import"})
+		return
+	}
+
+	// Tell apart a missing import from one that cannot be rolled back now
+	err = imports.FindOne(ctx, bson.M{"_id": jobID, "user_id": userID}).Decode(&job)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back import"})
+	case job.Status == models.ImportPending || job.Status == models.ImportRunning:
+		c.JSON(http.StatusConflict, gin.H{"error": "The import is still running"})
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "The import is already rolled back"})
+	}
+}
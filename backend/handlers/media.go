@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/config"
+	"unleashed-space/media"
+	"unleashed-space/models"
+	"unleashed-space/storage"
+)
+
+// maxAltTextLength is the longest accepted alt text, in characters
+const maxAltTextLength = 1500
+
+// mediaKeyPattern matches the keys generated by Upload
+var mediaKeyPattern = regexp.MustCompile(`^[0-9a-f]{24}(_thumb)?\.(jpg|png|gif)$`)
+
+// errInvalidMedia is returned when media IDs given for a post cannot be attached to it
+var errInvalidMedia = errors.New("media not found or already attached")
+
+type MediaHandler struct {
+	db    *mongo.Database
+	store storage.Storage
+}
+
+func NewMediaHandler(db *mongo.Database, store storage.Storage) *MediaHandler {
+	return &MediaHandler{db: db, store: store}
+}
+
+// Upload accepts a multipart "file" image and optional "alt_text". The returned
+// media ID can then be passed in media_ids when creating a post.
+func (h *MediaHandler) Upload(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	maxBytes := config.MediaMaxBytes()
+	tooLarge := gin.H{"error": "File is too large, the limit is " + strconv.FormatInt(maxBytes, 10) + " bytes"}
+
+	// Leave some room for the multipart framing around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	altText := c.PostForm("alt_text")
+	if utf8.RuneCountInString(altText) > maxAltTextLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alt text is too long"})
+		return
+	}
+
+	img, err := media.Process(data)
+	if err != nil {
+		switch err {
+		case media.ErrUnsupportedType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case media.ErrTooLarge:
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image"})
+		}
+		return
+	}
+
+	ctx := context.Background()
+	id := primitive.NewObjectID()
+	m := models.Media{
+		ID:           id,
+		UserID:       userID.(primitive.ObjectID),
+		Key:          id.Hex() + "." + img.Ext,
+		ThumbnailKey: id.Hex() + "_thumb." + img.ThumbExt,
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+		Size:         len(img.Data),
+		AltText:      altText,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := h.store.Put(ctx, m.Key, img.Data, img.ContentType); err != nil {
+		log.Printf("Error storing media %s: %v", m.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+	if err := h.store.Put(ctx, m.ThumbnailKey, img.Thumbnail, img.ThumbType); err != nil {
+		log.Printf("Error storing media %s: %v", m.ThumbnailKey, err)
+		h.deleteObjects(ctx, m.Key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	if _, err := h.db.Collection("media").InsertOne(ctx, m); err != nil {
+		h.deleteObjects(ctx, m.Key, m.ThumbnailKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, m)
+}
+
+// Serve streams a stored media file to callers who may see the post it is
+// attached to. Unattached uploads are only served to their owner.
+func (h *MediaHandler) Serve(c *gin.Context) {
+	key := c.Param("key")
+	if !mediaKeyPattern.MatchString(key) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	allowed, public, err := h.mayServe(c.Request.Context(), c, key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+
+	body, err := h.store.Open(c.Request.Context(), key)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		log.Printf("Error opening media %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
+		return
+	}
+	defer body.Close()
+
+	// Keys are never reused, so files of public posts can be cached forever.
+	// Others must be checked again on every request.
+	if public {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "private, no-store")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, mime.TypeByExtension(path.Ext(key)), body, nil)
+}
+
+// mayServe reports whether the caller may fetch the file stored under key, and
+// whether it belongs to a public post, so that anyone may cache it
+func (h *MediaHandler) mayServe(ctx context.Context, c *gin.Context, key string) (allowed, public bool, err error) {
+	id, err := primitive.ObjectIDFromHex(key[:24])
+	if err != nil {
+		return false, false, nil
+	}
+
+	var m models.Media
+	if err := h.db.Collection("media").FindOne(ctx, bson.M{"_id": id}).Decode(&m); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+
+	if m.PostID == nil {
+		userID, exists := c.Get("user_id")
+		return exists && userID.(primitive.ObjectID) == m.UserID, false, nil
+	}
+
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": *m.PostID})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	visible, err := canView(ctx, h.db, c, post)
+	if err != nil || !visible {
+		return false, false, err
+	}
+
+	// Ephemeral posts must not outlive their expiry in caches
+	return true, post.IsPublic() && post.IsPublished() && post.ExpiresAt == nil, nil
+}
+
+func (h *MediaHandler) deleteObjects(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := h.store.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting media %s: %v", key, err)
+		}
+	}
+}
+
+// attachMedia claims the caller's unattached uploads for a new post and returns
+// them as attachments in the order given. Nothing is claimed if any ID is invalid.
+func attachMedia(ctx context.Context, db *mongo.Database, userID, postID primitive.ObjectID, mediaIDs []string) ([]models.Attachment, error) {
+	if len(mediaIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(mediaIDs))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, s := range mediaIDs {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil || seen[id] {
+			return nil, errInvalidMedia
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	coll := db.Collection("media")
+	result, err := coll.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "user_id": userID, "post_id": nil},
+		bson.M{"$set": bson.M{"post_id": postID}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount != int64(len(ids)) {
+		detachMedia(ctx, db, postID)
+		return nil, errInvalidMedia
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{"post_id": postID})
+	if err != nil {
+		detachMedia(ctx, db, postID)
+		return nil, err
+	}
+	var found []models.Media
+	if err := cursor.All(ctx, &found); err != nil {
+		detachMedia(ctx, db, postID)
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Media, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+	attachments := make([]models.Attachment, 0, len(ids))
+	for _, id := range ids {
+		m := byID[id]
+		attachments = append(attachments, models.Attachment{
+			MediaID:      m.ID,
+			Key:          m.Key,
+			ThumbnailKey: m.ThumbnailKey,
+			ContentType:  m.ContentType,
+			Width:        m.Width,
+			Height:       m.Height,
+			AltText:      m.AltText,
+		})
+	}
+	return attachments, nil
+}
+
+// detachMedia releases media claimed for a post that was not created
+func detachMedia(ctx context.Context, db *mongo.Database, postID primitive.ObjectID) {
+	_, err := db.Collection("media").UpdateMany(ctx, bson.M{"post_id": postID}, bson.M{"$unset": bson.M{"post_id": ""}})
+	if err != nil {
+		log.Printf("Error detaching media from post %s: %v", postID.Hex(), err)
+	}
+}
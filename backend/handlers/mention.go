@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/content"
+	"unleashed-space/events"
+	"unleashed-space/models"
+)
+
+// resolveMentions turns the @username tokens in text into mentions of existing users.
+// Mentions keep the user ID, so they stay valid if the user is later renamed.
+func (h *PostHandler) resolveMentions(ctx context.Context, text string) ([]models.Mention, error) {
+	tokens := content.ExtractMentions(text)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	usernames := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		usernames = append(usernames, t.Username)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "username": 1})
+	cursor, err := h.db.Collection("users").Find(ctx, bson.M{"username": bson.M{"$in": usernames}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	byUsername := make(map[string]primitive.ObjectID, len(users))
+	for _, u := range users {
+		byUsername[u.Username] = u.ID
+	}
+
+	var mentions []models.Mention
+	for _, t := range tokens {
+		userID, ok := byUsername[t.Username]
+		if !ok {
+			continue
+		}
+		mentions = append(mentions, models.Mention{
+			UserID:   userID,
+			Username: t.Username,
+			Start:    t.Start,
+			End:      t.End,
+		})
+	}
+	return mentions, nil
+}
+
+// publishMentions emits a mention event for every user newly mentioned in post.
+// Users already mentioned in previous, and the author, are skipped. Private
+// posts notify nobody, since mentioned users cannot see them.
+func (h *PostHandler) publishMentions(ctx context.Context, post models.Post, previous []models.Mention) {
+	if post.Visibility == models.VisibilityPrivate {
+		return
+	}
+
+	notified := map[primitive.ObjectID]bool{post.UserID: true}
+	for _, m := range previous {
+		notified[m.UserID] = true
+	}
+
+	var evts []models.Event
+	for _, m := range post.Mentions {
+		if notified[m.UserID] {
+			continue
+		}
+		notified[m.UserID] = true
+		evts = append(evts, models.Event{
+			Type:    models.EventMention,
+			UserID:  m.UserID,
+			ActorID: post.UserID,
+			PostID:  post.ID,
+		})
+	}
+
+	if err := events.Publish(ctx, h.db, evts...); err != nil {
+		log.Printf("Error publishing mention events for post %s: %v", post.ID.Hex(), err)
+	}
+}
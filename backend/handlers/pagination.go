@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor identifies a position in a listing ordered by (created_at, _id)
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// encode returns the opaque form handed out to clients
+func (p pageCursor) encode() string {
+	raw := strconv.FormatInt(p.CreatedAt.UnixMilli(), 10) + ":" + p.ID.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePageCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, err
+	}
+
+	millis, hex, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return pageCursor{}, err
+	}
+
+	return pageCursor{CreatedAt: time.UnixMilli(ms), ID: id}, nil
+}
+
+// pageRequest holds the limit/before/after query parameters of a listing.
+// Listings are newest first: before pages towards older items, after towards newer ones.
+type pageRequest struct {
+	Limit  int
+	Before *pageCursor
+	After  *pageCursor
+}
+
+// pageInfo holds the cursors returned alongside a page
+type pageInfo struct {
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+}
+
+func parsePageRequest(c *gin.Context) (pageRequest, error) {
+	page := pageRequest{Limit: defaultPageLimit}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return page, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+		}
+		page.Limit = n
+	}
+
+	before, after := c.Query("before"), c.Query("after")
+	if before != "" && after != "" {
+		return page, errors.New("before and after cannot be combined")
+	}
+	if before != "" {
+		cur, err := decodePageCursor(before)
+		if err != nil {
+			return page, errors.New("invalid before cursor")
+		}
+		page.Before = &cur
+	}
+	if after != "" {
+		cur, err := decodePageCursor(after)
+		if err != nil {
+			return page, errors.New("invalid after cursor")
+		}
+		page.After = &cur
+	}
+
+	return page, nil
+}
+
+// findPage runs a keyset-paginated query over (created_at, _id) on coll.
+// Because the position is anchored to an existing key rather than an offset,
+// inserts between requests never shift or duplicate items across pages.
+func findPage[T any](ctx context.Context, coll *mongo.Collection, filter bson.M, page pageRequest, key func(T) pageCursor) ([]T, pageInfo, error) {
+	direction := -1
+	var keyset bson.M
+	switch {
+	case page.Before != nil:
+		keyset = keysetFilter("$lt", *page.Before)
+	case page.After != nil:
+		// Walk forwards from the cursor, then flip the page back to newest first
+		direction = 1
+		keyset = keysetFilter("$gt", *page.After)
+	}
+	if keyset != nil {
+		filter = bson.M{"$and": []bson.M{filter, keyset}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(page.Limit + 1))
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, pageInfo{}, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, pageInfo{}, err
+	}
+
+	hasMore := len(items) > page.Limit
+	if hasMore {
+		items = items[:page.Limit]
+	}
+	if direction == 1 {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	var info pageInfo
+	if len(items) > 0 {
+		first := key(items[0]).encode()
+		last := key(items[len(items)-1]).encode()
+
+		// prev_cursor is always given so clients can poll for newer items
+		info.PrevCursor = &first
+		if page.After != nil || hasMore {
+			info.NextCursor = &last
+		}
+	}
+
+	return items, info, nil
+}
+
+func keysetFilter(op string, cur pageCursor) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"created_at": bson.M{op: cur.CreatedAt}},
+		{"created_at": cur.CreatedAt, "_id": bson.M{op: cur.ID}},
+	}}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testCursor() pageCursor {
+	id, _ := primitive.ObjectIDFromHex("64b7f0c2a1b2c3d4e5f60718")
+	return pageCursor{CreatedAt: time.UnixMilli(1700000000123), ID: id}
+}
+
+func TestPageCursorRoundTrip(t *testing.T) {
+	cur := testCursor()
+	got, err := decodePageCursor(cur.encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(cur.CreatedAt) || got.ID != cur.ID {
+		t.Errorf("decoded %+v, want %+v", got, cur)
+	}
+
+	// Cursors keep millisecond precision only
+	cur.CreatedAt = cur.CreatedAt.Add(456 * time.Microsecond)
+	if got, _ := decodePageCursor(cur.encode()); !got.CreatedAt.Equal(time.UnixMilli(1700000000123)) {
+		t.Errorf("created_at %v, want it truncated to milliseconds", got.CreatedAt)
+	}
+}
+
+func TestDecodePageCursorInvalid(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	cases := map[string]string{
+		"not base64":   "!!!",
+		"padded":       base64.URLEncoding.EncodeToString([]byte("1:64b7f0c2a1b2c3d4e5f60718")),
+		"no separator": enc("1700000000123"),
+		"bad millis":   enc("abc:64b7f0c2a1b2c3d4e5f60718"),
+		"bad id":       enc("1700000000123:xyz"),
+		"short id":     enc("1700000000123:64b7f0"),
+		"empty":        "",
+	}
+	for name, s := range cases {
+		if _, err := decodePageCursor(s); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	cur := testCursor()
+	for _, op := range []string{"$lt", "$gt"} {
+		want := bson.M{"$or": []bson.M{
+			{"created_at": bson.M{op: cur.CreatedAt}},
+			{"created_at": cur.CreatedAt, "_id": bson.M{op: cur.ID}},
+		}}
+		if got := keysetFilter(op, cur); !reflect.DeepEqual(got, want) {
+			t.Errorf("keysetFilter(%s) = %v, want %v", op, got, want)
+		}
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cur := testCursor().encode()
+
+	cases := []struct {
+		query  string
+		limit  int
+		before bool
+		after  bool
+		ok     bool
+	}{
+		{"", defaultPageLimit, false, false, true},
+		{"limit=1", 1, false, false, true},
+		{"limit=100", maxPageLimit, false, false, true},
+		{"limit=0", 0, false, false, false},
+		{"limit=101", 0, false, false, false},
+		{"limit=-5", 0, false, false, false},
+		{"limit=ten", 0, false, false, false},
+		{"before=" + cur, defaultPageLimit, true, false, true},
+		{"after=" + cur + "&limit=5", 5, false, true, true},
+		{"before=" + cur + "&after=" + cur, 0, false, false, false},
+		{"before=nope", 0, false, false, false},
+		{"after=nope", 0, false, false, false},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+
+		page, err := parsePageRequest(c)
+		if (err == nil) != tc.ok {
+			t.Errorf("%q: error %v, want ok %v", tc.query, err, tc.ok)
+			continue
+		}
+		if !tc.ok {
+			continue
+		}
+		if page.Limit != tc.limit || (page.Before != nil) != tc.before || (page.After != nil) != tc.after {
+			t.Errorf("%q: got %+v", tc.query, page)
+		}
+	}
+}
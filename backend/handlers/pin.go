@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/config"
+	"unleashed-space/models"
+)
+
+// PinPost pins one of the caller's published posts to their profile, first
+// or at the given position. Pinning an already pinned post moves it.
+func (h *PostHandler) PinPost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// The body is optional
+	var input models.PinPostInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+
+	var post models.Post
+	err = h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": postID, "status": nil})).Decode(&post)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if post.UserID != userID.(primitive.ObjectID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only pin your own posts"})
+		return
+	}
+	if post.RepostOf != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reposts cannot be pinned"})
+		return
+	}
+
+	position := 0
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	// Moving a pinned post takes it out first, so it does not count twice
+	// towards the limit. The filter on the last allowed slot keeps the limit
+	// even when several pins race.
+	users := h.db.Collection("users")
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$pull": bson.M{"pinned_posts": postID}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+	limit := config.MaxPinnedPosts()
+	var user models.User
+	err = users.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "pinned_posts." + strconv.Itoa(limit-1): bson.M{"$exists": false}},
+		bson.M{"$push": bson.M{"pinned_posts": bson.M{"$each": []primitive.ObjectID{postID}, "$position": position}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "You can pin at most " + strconv.Itoa(limit) + " posts"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned_posts": user.PinnedPosts})
+}
+
+// UnpinPost removes a post from the caller's pinned posts. Unpinning a post
+// that is not pinned is a no-op.
+func (h *PostHandler) UnpinPost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var user models.User
+	err = h.db.Collection("users").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": userID},
+		bson.M{"$pull": bson.M{"pinned_posts": postID}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned_posts": user.PinnedPosts})
+}
+
+// ReorderPins sets the order of the caller's pinned posts. post_ids must list
+// exactly the posts that are pinned.
+func (h *PostHandler) ReorderPins(c *gin.Context) {
+	var input models.ReorderPinsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(input.PostIDs))
+	seen := make(map[primitive.ObjectID]bool, len(input.PostIDs))
+	for _, hex := range input.PostIDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil || seen[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "post_ids must be distinct post IDs"})
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	// Only replace the list if it still holds the same posts
+	var user models.User
+	err := h.db.Collection("users").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": userID, "pinned_posts": bson.M{"$size": len(ids), "$all": ids}},
+		bson.M{"$set": bson.M{"pinned_posts": ids}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "post_ids must list exactly your pinned posts"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder pinned posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned_posts": user.PinnedPosts})
+}
+
+// GetUserProfile is the public profile of a user: their pinned posts
+// followed by their other posts, paginated like the feed. Only posts the
+// caller may see are included.
+func (h *PostHandler) GetUserProfile(c *gin.Context) {
+	h.userProfile(c, bson.M{"username": c.Param("username")})
+}
+
+// GetUserProfileByID is GetUserProfile for a user ID. Mention links point
+// here, as they must keep working after the user changes their username.
+func (h *PostHandler) GetUserProfileByID(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.userProfile(c, bson.M{"_id": userID})
+}
+
+func (h *PostHandler) userProfile(c *gin.Context, userFilter bson.M) {
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(ctx, userFilter).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if user.IsSuspended() && !models.IsModeratorRole(c.GetString("user_role")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	filter, err := h.publicPostsFilter(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	filter["user_id"] = user.ID
+
+	pinned, err := h.pinnedPosts(ctx, c, user.PinnedPosts, copyFilter(filter))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if c.Query("include_replies") != "true" {
+		filter["in_reply_to"] = nil
+	}
+	if len(user.PinnedPosts) > 0 {
+		filter["_id"] = bson.M{"$nin": user.PinnedPosts}
+	}
+	posts, info, err := h.findPostPage(ctx, c, filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	profile := gin.H{
+		"id":              user.ID,
+		"name":            user.Name,
+		"username":        user.Username,
+		"followers_count": user.FollowersCount,
+		"following_count": user.FollowingCount,
+		"created_at":      user.CreatedAt,
+	}
+	if viewerID, exists := c.Get("user_id"); exists && viewerID.(primitive.ObjectID) != user.ID {
+		relationships, err := viewerRelationships(ctx, h.db, viewerID.(primitive.ObjectID), []primitive.ObjectID{user.ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			return
+		}
+		profile["relationship"] = relationships[user.ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":        profile,
+		"pinned":      pinned,
+		"posts":       posts,
+		"next_cursor": info.NextCursor,
+		"prev_cursor": info.PrevCursor,
+	})
+}
+
+// pinnedPosts loads the pinned posts with the given IDs that match filter, in
+// pin order and prepared for the caller
+func (h *PostHandler) pinnedPosts(ctx context.Context, c *gin.Context, ids []primitive.ObjectID, filter bson.M) ([]models.Post, error) {
+	if len(ids) == 0 {
+		return []models.Post{}, nil
+	}
+
+	filter["_id"] = bson.M{"$in": ids}
+	cursor, err := h.db.Collection("posts").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Post
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	posts := make([]models.Post, 0, len(found))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			p.Pinned = true
+			posts = append(posts, p)
+		}
+	}
+
+	if err := h.prepare(ctx, c, postPtrs(posts)...); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
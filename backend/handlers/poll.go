@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
+)
+
+const (
+	minPollDuration = 5 * time.Minute
+	maxPollDuration = 30 * 24 * time.Hour
+)
+
+// newPoll validates poll input for a post going live at start
+func newPoll(input models.PollInput, start time.Time) (*models.Poll, error) {
+	duration := input.ClosesAt.Sub(start)
+	if duration < minPollDuration || duration > maxPollDuration {
+		return nil, errors.New("poll closes_at must be between 5 minutes and 30 days after the post is published")
+	}
+
+	poll := &models.Poll{Multiple: input.Multiple, ClosesAt: input.ClosesAt, Duration: duration}
+	seen := make(map[string]bool, len(input.Options))
+	for _, title := range input.Options {
+		if seen[title] {
+			return nil, errors.New("poll options must be distinct")
+		}
+		seen[title] = true
+		poll.Options = append(poll.Options, models.PollOption{Title: title})
+	}
+	return poll, nil
+}
+
+// pollClosesAt is when a poll closes if its post goes live at start. Polls keep
+// the duration they were created with; for polls from before the duration was
+// recorded, the original closing time is moved into the allowed window.
+func pollClosesAt(poll *models.Poll, start time.Time) time.Time {
+	if poll.Duration > 0 {
+		return start.Add(poll.Duration)
+	}
+	duration := poll.ClosesAt.Sub(start)
+	if duration < minPollDuration {
+		duration = minPollDuration
+	}
+	if duration > maxPollDuration {
+		duration = maxPollDuration
+	}
+	return start.Add(duration)
+}
+
+// VotePoll casts the caller's vote in the poll of a post. Every user votes
+// once, and votes cannot be changed afterwards.
+func (h *PostHandler) VotePoll(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var input models.VoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	if err := posts.FindOne(ctx, liveFilter(bson.M{"_id": postID, "status": nil})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	hidden, err := h.postHidden(ctx, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if hidden || post.Poll == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Poll not found"})
+		return
+	}
+	if post.Poll.IsClosed() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This poll is closed"})
+		return
+	}
+
+	if len(input.Choices) > 1 && !post.Poll.Multiple {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This poll allows a single choice"})
+		return
+	}
+	inc := bson.M{"poll.voter_count": 1}
+	for _, choice := range input.Choices {
+		key := "poll.options." + strconv.Itoa(choice) + ".votes"
+		if choice < 0 || choice >= len(post.Poll.Options) || inc[key] != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Choices must be distinct option indexes"})
+			return
+		}
+		inc[key] = 1
+	}
+
+	// The unique index on poll_votes is what stops double voting
+	vote := models.PollVote{
+		PostID:    postID,
+		UserID:    userID.(primitive.ObjectID),
+		Choices:   input.Choices,
+		CreatedAt: time.Now(),
+	}
+	result, err := h.db.Collection("poll_votes").InsertOne(ctx, vote)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already voted in this poll"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+
+	// Count the vote only while the poll is open; otherwise take the vote back
+	update, err := posts.UpdateOne(ctx,
+		bson.M{"_id": postID, "poll.closed": false, "poll.closes_at": bson.M{"$gt": time.Now()}},
+		bson.M{"$inc": inc},
+	)
+	if err != nil || update.MatchedCount == 0 {
+		if _, delErr := h.db.Collection("poll_votes").DeleteOne(ctx, bson.M{"_id": result.InsertedID}); delErr != nil {
+			log.Printf("Error removing uncounted vote in post %s: %v", postID.Hex(), delErr)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "This poll is closed"})
+		return
+	}
+
+	if err := posts.FindOne(ctx, bson.M{"_id": postID}).Decode(&post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if err := h.prepare(ctx, c, &post); err != nil {
+		log.Printf("Error preparing post %s: %v", post.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// viewerPollVotes returns the choices userID made in the polls of the given posts
+func viewerPollVotes(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID][]int, error) {
+	cursor, err := db.Collection("poll_votes").Find(ctx, bson.M{"user_id": userID, "post_id": bson.M{"$in": postIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	var votes []models.PollVote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[primitive.ObjectID][]int, len(votes))
+	for _, v := range votes {
+		byPost[v.PostID] = v.Choices
+	}
+	return byPost, nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"time"
 
@@ -11,15 +12,21 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"unleashed-space/config"
+	"unleashed-space/content"
+	"unleashed-space/linkpreview"
 	"unleashed-space/models"
+	"unleashed-space/search"
 )
 
 type PostHandler struct {
-	db *mongo.Database
+	db       *mongo.Database
+	previews *linkpreview.Service
+	search   search.Engine
 }
 
-func NewPostHandler(db *mongo.Database) *PostHandler {
-	return &PostHandler{db: db}
+func NewPostHandler(db *mongo.Database, engine search.Engine) *PostHandler {
+	return &PostHandler{db: db, previews: linkpreview.NewService(db), search: engine}
 }
 
 func (h *PostHandler) CreatePost(c *gin.Context) {
@@ -37,6 +44,19 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
+	if input.Draft && input.PublishAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A post cannot be both a draft and scheduled"})
+		return
+	}
+	if input.PublishAt != nil && !input.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+		return
+	}
+	if input.ExpiresAt != nil && (!input.ExpiresAt.After(time.Now()) || input.PublishAt != nil && !input.ExpiresAt.After(*input.PublishAt)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future and after publish_at"})
+		return
+	}
+
 	// Get user details for post author
 	var user models.User
 	err := h.db.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
@@ -48,46 +68,185 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	// Create post
 	now := time.Now()
 	post := models.Post{
-		UserID:  userID.(primitive.ObjectID),
-		Content: input.Content,
-		Author: models.PostAuthor{
-			Name:     user.Name,
-			Username: user.Username,
-		},
-		CreatedAt: now,
-		UpdatedAt: now,
+		UserID:     userID.(primitive.ObjectID),
+		Content:    input.Content,
+		Tags:       content.ExtractHashtags(input.Content),
+		Author:     user.AsAuthor(),
+		Visibility: input.Visibility,
+		Warning:    input.Warning,
+		Sensitive:  input.Sensitive,
+		ExpiresAt:  input.ExpiresAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if post.Visibility == "" {
+		post.Visibility = models.VisibilityPublic
+	}
+	switch {
+	case input.Draft:
+		post.Status = models.PostStatusDraft
+	case input.PublishAt != nil:
+		post.Status = models.PostStatusScheduled
+		post.PublishAt = input.PublishAt
+	}
+
+	if input.Poll != nil {
+		start := now
+		if post.PublishAt != nil {
+			start = *post.PublishAt
+		}
+		post.Poll, err = newPoll(*input.Poll, start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	post.Mentions, err = h.resolveMentions(context.Background(), input.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+		return
+	}
+	post.ContentHTML = post.RenderContent()
+
+	// Attach replies to their conversation
+	if input.InReplyTo != "" {
+		parentID, err := primitive.ObjectIDFromHex(input.InReplyTo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid in_reply_to post ID"})
+			return
+		}
+
+		// Replying to a repost joins the original conversation
+		parent, err := h.findOriginal(context.Background(), parentID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Post being replied to not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post being replied to"})
+			return
+		}
+		if visible, err := canView(context.Background(), h.db, c, parent); err != nil || !visible {
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post being replied to"})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post being replied to not found"})
+			return
+		}
+
+		post.InReplyTo = &parent.ID
+		post.Ancestors = append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID)
+		post.RootID = &post.Ancestors[0]
+		post.Depth = parent.Depth + 1
+	}
+
+	// Quote posts embed the post they comment on
+	if input.QuoteOf != "" {
+		quotedID, err := primitive.ObjectIDFromHex(input.QuoteOf)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quote_of post ID"})
+			return
+		}
+
+		quoted, err := h.findOriginal(context.Background(), quotedID)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Quoted post not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quoted post"})
+			return
+		}
+		if visible, err := canView(context.Background(), h.db, c, quoted); err != nil || !visible {
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quoted post"})
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "Quoted post not found"})
+			return
+		}
+		if !quoted.IsPublic() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only public posts can be quoted"})
+			return
+		}
+		post.QuoteOf = &quoted.ID
+	}
+
+	h.setPreviewURL(context.Background(), &post)
+
+	// Claim uploaded media, which needs the post ID up front
+	post.ID = primitive.NewObjectID()
+	post.Attachments, err = attachMedia(context.Background(), h.db, post.UserID, post.ID, input.MediaIDs)
+	if err != nil {
+		if err == errInvalidMedia {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Media not found or already attached to a post"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach media"})
+		return
 	}
 
 	result, err := h.db.Collection("posts").InsertOne(context.Background(), post)
 	if err != nil {
+		detachMedia(context.Background(), h.db, post.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
 
 	post.ID = result.InsertedID.(primitive.ObjectID)
+
+	// Drafts and scheduled posts get these once they are published
+	if post.IsPublished() {
+		h.published(context.Background(), post)
+	}
+
+	if err := h.prepare(context.Background(), c, &post); err != nil {
+		log.Printf("Error preparing post %s: %v", post.ID.Hex(), err)
+	}
+
 	c.JSON(http.StatusCreated, post)
 }
 
+// GetPosts is the caller's home timeline: their own posts and reposts and
+// those of the users they follow, newest first.
 func (h *PostHandler) GetPosts(c *gin.Context) {
-	// Set up options for pagination and sorting
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
-		SetLimit(20)
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	cursor, err := h.db.Collection("posts").Find(context.Background(), bson.M{}, opts)
+	ctx := context.Background()
+	followed, err := followedUserIDs(ctx, h.db, userID.(primitive.ObjectID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
 		return
 	}
-	defer cursor.Close(context.Background())
+	authors := append(append([]primitive.ObjectID{}, followed...), userID.(primitive.ObjectID))
 
-	var posts []models.Post
-	if err := cursor.All(context.Background(), &posts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode posts"})
+	// Hide posts from suspended authors and posts the caller may not see
+	filter := postsFilter(c, followed)
+	filter["$and"] = append(filter["$and"].([]bson.M), bson.M{"user_id": bson.M{"$in": authors}})
+	if c.Query("include_replies") != "true" {
+		filter["in_reply_to"] = nil
+	}
+
+	posts, info, err := h.findPostPage(ctx, c, filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	c.JSON(http.StatusOK, gin.H{"posts": posts, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
 }
 
 func (h *PostHandler) GetUserPosts(c *gin.Context) {
@@ -98,36 +257,387 @@ func (h *PostHandler) GetUserPosts(c *gin.Context) {
 		return
 	}
 
-	// Set up options for pagination and sorting
-	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
-		SetLimit(20)
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := context.Background()
 
-	cursor, err := h.db.Collection("posts").Find(context.Background(), bson.M{"user_id": userID}, opts)
+	// Pinned posts come first and are left out of the paginated posts
+	var user models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user posts"})
+		return
+	}
+	filter := liveFilter(bson.M{"user_id": userID, "status": nil})
+	pinned, err := h.pinnedPosts(ctx, c, user.PinnedPosts, copyFilter(filter))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user posts"})
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var posts []models.Post
-	if err := cursor.All(context.Background(), &posts); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode posts"})
+	if c.Query("include_replies") != "true" {
+		filter["in_reply_to"] = nil
+	}
+	if len(user.PinnedPosts) > 0 {
+		filter["_id"] = bson.M{"$nin": user.PinnedPosts}
+	}
+	posts, info, err := h.findPostPage(ctx, c, filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	c.JSON(http.StatusOK, gin.H{"pinned": pinned, "posts": posts, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
 }
 
 func (h *PostHandler) GetPublicFeed(c *gin.Context) {
-	// Set up options for pagination and sorting
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Hide posts from suspended authors and posts the caller may not see
+	filter, err := h.publicPostsFilter(context.Background(), c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	if c.Query("include_replies") != "true" {
+		filter["in_reply_to"] = nil
+	}
+
+	posts, info, err := h.findPostPage(context.Background(), c, filter, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"posts": posts, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
+}
+
+func (h *PostHandler) GetPost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	ctx := context.Background()
+
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, bson.M{"_id": postID}).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	// Only callers who may see the post learn that it was deleted or expired
+	hidden, err := h.postHidden(ctx, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if post.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Post has been deleted", "deleted_at": post.DeletedAt})
+		return
+	}
+	if post.IsExpired() {
+		c.JSON(http.StatusGone, gin.H{"error": "Post has expired", "expires_at": post.ExpiresAt})
+		return
+	}
+
+	if err := h.prepare(ctx, c, &post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+func (h *PostHandler) UpdatePost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var input models.UpdatePostInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	if err := posts.FindOne(ctx, liveFilter(bson.M{"_id": postID})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if post.UserID != userID.(primitive.ObjectID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own posts"})
+		return
+	}
+	if post.RepostOf != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reposts cannot be edited"})
+		return
+	}
+	if post.Content == input.Content {
+		c.JSON(http.StatusOK, post)
+		return
+	}
+
+	// Only apply the edit if nobody changed the post since we read it
+	now := time.Now()
+	tags := content.ExtractHashtags(input.Content)
+	mentions, err := h.resolveMentions(ctx, input.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+		return
+	}
+	set := bson.M{
+		"content":      input.Content,
+		"content_html": models.Post{Content: input.Content, Mentions: mentions}.RenderContent(),
+		"tags":         tags,
+		"mentions":     mentions,
+		"updated_at":   now,
+	}
+	update := bson.M{"$set": set}
+
+	// Drafts can be reworked freely; only published posts keep an edit history
+	if post.IsPublished() {
+		set["edited"] = true
+		set["edited_at"] = now
+	}
+
+	// A different first link needs a new preview
+	edited := models.Post{Content: input.Content}
+	fetchPreview := h.setPreviewURL(ctx, &edited)
+	switch {
+	case edited.PreviewURL == post.PreviewURL:
+	case edited.PreviewURL == "":
+		update["$unset"] = bson.M{"preview_url": "", "link_preview": ""}
+	default:
+		set["preview_url"] = edited.PreviewURL
+		set["link_preview"] = edited.LinkPreview
+	}
+
+	// Keep the replaced content as a revision. It is saved before the edit so
+	// the previous text cannot be lost, and removed again if the edit fails.
+	revisions := h.db.Collection("post_revisions")
+	var revisionID primitive.ObjectID
+	if post.IsPublished() {
+		revision := models.PostRevision{
+			ID:         primitive.NewObjectID(),
+			PostID:     postID,
+			Content:    post.Content,
+			WrittenAt:  post.UpdatedAt,
+			ReplacedAt: now,
+		}
+		if _, err := revisions.InsertOne(ctx, revision); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save revision"})
+			return
+		}
+		revisionID = revision.ID
+	}
+
+	result := posts.FindOneAndUpdate(
+		ctx,
+		liveFilter(bson.M{"_id": postID, "updated_at": post.UpdatedAt}),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var updated models.Post
+	if err := result.Decode(&updated); err != nil {
+		if !revisionID.IsZero() {
+			if _, err := revisions.DeleteOne(ctx, bson.M{"_id": revisionID}); err != nil {
+				log.Printf("Error removing revision %s of post %s: %v", revisionID.Hex(), postID.Hex(), err)
+			}
+		}
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Post was modified concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+
+	// Unpublished posts get their side effects when they are published
+	if !updated.IsPublished() {
+		c.JSON(http.StatusOK, updated)
+		return
+	}
+
+	if updated.IsPublic() {
+		h.recordTagUsage(ctx, addedTags(post.Tags, tags))
+	}
+	h.publishMentions(ctx, updated, post.Mentions)
+	if fetchPreview && edited.PreviewURL != post.PreviewURL {
+		h.previews.AttachAsync(postID, edited.PreviewURL)
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (h *PostHandler) GetPostRevisions(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	ctx := context.Background()
+
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": postID})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	hidden, err := h.postHidden(ctx, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "replaced_at", Value: -1}})
+	cursor, err := h.db.Collection("post_revisions").Find(ctx, bson.M{"post_id": postID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.PostRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": post, "revisions": revisions})
+}
+
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": postID})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if post.UserID != userID.(primitive.ObjectID) && !models.IsModeratorRole(c.GetString("user_role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own posts"})
+		return
+	}
+
+	// Deleting a repost is the same as undoing it
+	if post.RepostOf != nil {
+		if err := h.removeRepost(ctx, post.UserID, *post.RepostOf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Repost removed"})
+		return
+	}
+
+	// The post as it was just before deletion tells whether it was counted,
+	// even if it was published in the meantime
+	now := time.Now()
+	var deleted models.Post
+	err = h.db.Collection("posts").FindOneAndUpdate(ctx, liveFilter(bson.M{"_id": postID}), bson.M{
+		"$set": bson.M{
+			"deleted_at": now,
+			"deleted_by": userID,
+		},
+	}).Decode(&deleted)
+	switch {
+	case err == nil:
+		if deleted.IsPublished() {
+			h.adjustCounters(ctx, deleted, -1)
+		}
+		// Bookmarks stay until the post is purged, so a restore brings them back.
+		// Pins are not restored: the author pins the post again if they want it.
+		if _, err := h.db.Collection("users").UpdateOne(ctx, bson.M{"_id": deleted.UserID}, bson.M{"$pull": bson.M{"pinned_posts": postID}}); err != nil {
+			log.Printf("Error unpinning post %s: %v", postID.Hex(), err)
+		}
+	case err != mongo.ErrNoDocuments:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Post deleted",
+		"restore_until": now.Add(config.TrashRetention()),
+	})
+}
+
+func (h *PostHandler) GetTrash(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	filter := bson.M{
+		"user_id":    userID,
+		"deleted_at": bson.M{"$gt": time.Now().Add(-config.TrashRetention())},
+	}
 	opts := options.Find().
-		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
 		SetLimit(20)
 
-	cursor, err := h.db.Collection("posts").Find(context.Background(), bson.M{}, opts)
+	cursor, err := h.db.Collection("posts").Find(context.Background(), filter, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted posts"})
 		return
 	}
 	defer cursor.Close(context.Background())
@@ -140,3 +650,227 @@ func (h *PostHandler) GetPublicFeed(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"posts": posts})
 }
+
+func (h *PostHandler) RestorePost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":        postID,
+		"deleted_at": bson.M{"$gt": time.Now().Add(-config.TrashRetention())},
+		"expires_at": bson.M{"$not": bson.M{"$lte": time.Now()}},
+	}
+
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, filter).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	// Authors cannot undo a moderator's removal
+	isModerator := models.IsModeratorRole(c.GetString("user_role"))
+	if !isModerator {
+		if post.UserID != userID.(primitive.ObjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only restore your own posts"})
+			return
+		}
+		if post.DeletedBy != nil && *post.DeletedBy != post.UserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This post was removed by a moderator"})
+			return
+		}
+	}
+
+	result := h.db.Collection("posts").FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	var restored models.Post
+	if err := result.Decode(&restored); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found in trash"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore post"})
+		return
+	}
+	if restored.IsPublished() {
+		h.adjustCounters(ctx, restored, 1)
+	}
+
+	c.JSON(http.StatusOK, restored)
+}
+
+// annotate fills in the viewer-specific fields of posts for signed-in callers.
+// Handlers should call prepare, which also covers embedded posts.
+func (h *PostHandler) annotate(ctx context.Context, c *gin.Context, posts ...*models.Post) error {
+	userID, exists := c.Get("user_id")
+	if !exists || len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+
+	reactions, err := viewerReactions(ctx, h.db, userID.(primitive.ObjectID), ids)
+	if err != nil {
+		return err
+	}
+	bookmarks, err := viewerBookmarks(ctx, h.db, userID.(primitive.ObjectID), ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		p.ViewerReactions = reactions[p.ID]
+		bookmarked := bookmarks[p.ID]
+		p.Bookmarked = &bookmarked
+	}
+
+	var pollIDs []primitive.ObjectID
+	for _, p := range posts {
+		if p.Poll != nil {
+			pollIDs = append(pollIDs, p.ID)
+		}
+	}
+	if len(pollIDs) > 0 {
+		votes, err := viewerPollVotes(ctx, h.db, userID.(primitive.ObjectID), pollIDs)
+		if err != nil {
+			return err
+		}
+		for _, p := range posts {
+			if choices, ok := votes[p.ID]; ok && p.Poll != nil {
+				p.Poll.Voted = true
+				p.Poll.ViewerChoices = choices
+			}
+		}
+	}
+
+	return nil
+}
+
+// setPreviewURL records the first link of the post as the one to preview and
+// fills in the preview from the cache. It reports whether a fetch is still needed.
+func (h *PostHandler) setPreviewURL(ctx context.Context, post *models.Post) bool {
+	urls := content.ExtractURLs(post.Content)
+	if len(urls) == 0 {
+		return false
+	}
+
+	post.PreviewURL = urls[0]
+	preview, cached := h.previews.Cached(ctx, post.PreviewURL)
+	post.LinkPreview = preview
+	return !cached
+}
+
+// addedTags returns the tags in after that were not already in before
+func addedTags(before, after []string) []string {
+	var added []string
+	for _, tag := range after {
+		found := false
+		for _, old := range before {
+			if old == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			added = append(added, tag)
+		}
+	}
+	return added
+}
+
+// postPtrs returns pointers into posts so they can be annotated in place
+func postPtrs(posts []models.Post) []*models.Post {
+	ptrs := make([]*models.Post, len(posts))
+	for i := range posts {
+		ptrs[i] = &posts[i]
+	}
+	return ptrs
+}
+
+// adjustCounter keeps a denormalized counter such as reply_count in step with the posts it counts
+func (h *PostHandler) adjustCounter(ctx context.Context, postID primitive.ObjectID, field string, delta int) {
+	_, err := h.db.Collection("posts").UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$inc": bson.M{field: delta}})
+	if err != nil {
+		log.Printf("Error updating %s of post %s: %v", field, postID.Hex(), err)
+	}
+}
+
+// adjustCounters updates the reply and quote counts of the posts that post replies to or quotes
+func (h *PostHandler) adjustCounters(ctx context.Context, post models.Post, delta int) {
+	if post.InReplyTo != nil {
+		h.adjustCounter(ctx, *post.InReplyTo, "reply_count", delta)
+	}
+	if post.QuoteOf != nil {
+		h.adjustCounter(ctx, *post.QuoteOf, "quote_count", delta)
+	}
+}
+
+// postHidden reports whether the post must be hidden from the caller, because
+// its visibility excludes them or its author is suspended. Moderators still see
+// posts of suspended authors.
+func (h *PostHandler) postHidden(ctx context.Context, c *gin.Context, post models.Post) (bool, error) {
+	visible, err := canView(ctx, h.db, c, post)
+	if err != nil || !visible {
+		return !visible, err
+	}
+	if models.IsModeratorRole(c.GetString("user_role")) {
+		return false, nil
+	}
+
+	var author models.User
+	err = h.db.Collection("users").FindOne(ctx, bson.M{"_id": post.UserID}).Decode(&author)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	return author.IsSuspended(), nil
+}
+
+// publicPostsFilter matches posts that may be shown to the caller in shared
+// listings: live, published posts by authors who are not suspended, within
+// their visibility
+func (h *PostHandler) publicPostsFilter(ctx context.Context, c *gin.Context) (bson.M, error) {
+	followed, err := viewerFollows(ctx, h.db, c)
+	if err != nil {
+		return nil, err
+	}
+	return postsFilter(c, followed), nil
+}
+
+// postsFilter is publicPostsFilter for a caller whose follows are loaded already
+func postsFilter(c *gin.Context, followed []primitive.ObjectID) bson.M {
+	return liveFilter(bson.M{"status": nil, "author_suspended": bson.M{"$ne": true}, "$and": []bson.M{visibilityFilter(c, followed)}})
+}
+
+// postCursor is the pagination key of a post
+func postCursor(p models.Post) pageCursor {
+	return pageCursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+// liveFilter restricts a post query to posts that have not been deleted and
+// have not expired, even if the expiry job has not removed them yet
+func liveFilter(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	filter["expires_at"] = bson.M{"$not": bson.M{"$lte": time.Now()}}
+	return filter
+}
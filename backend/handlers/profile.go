@@ -39,10 +39,14 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":       user.ID,
-			"name":     user.Name,
-			"username": user.Username,
-			"email":    user.Email,
+			"id":              user.ID,
+			"name":            user.Name,
+			"username":        user.Username,
+			"email":           user.Email,
+			"pinned_posts":    user.PinnedPosts,
+			"preferences":     user.Preferences,
+			"followers_count": user.FollowersCount,
+			"following_count": user.FollowingCount,
 		},
 	})
 }
@@ -99,6 +103,13 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		update["$set"].(bson.M)["email"] = input.Email
 	}
 
+	// Posts keep a copy of the name and username; the author sync job
+	// updates them once the new profile version is recorded
+	if input.Name != "" || input.Username != "" {
+		update["$inc"] = bson.M{"profile_version": 1}
+		update["$set"].(bson.M)["author_sync_pending"] = true
+	}
+
 	// Update user
 	result := h.db.Collection("users").FindOneAndUpdate(
 		context.Background(),
@@ -122,3 +133,42 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 		},
 	})
 }
+
+// UpdatePreferences changes how posts are presented to the caller
+func (h *ProfileHandler) UpdatePreferences(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var input models.UpdatePreferencesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only update preferences that were provided
+	set := bson.M{"updated_at": time.Now()}
+	if input.ExpandWarnings != nil {
+		set["preferences.expand_warnings"] = *input.ExpandWarnings
+	}
+	if input.ShowSensitive != nil {
+		set["preferences.show_sensitive"] = *input.ShowSensitive
+	}
+
+	var user models.User
+	err := h.db.Collection("users").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": userID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": user.Preferences})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/config"
+	"unleashed-space/models"
+)
+
+type ReactionHandler struct {
+	db *mongo.Database
+}
+
+func NewReactionHandler(db *mongo.Database) *ReactionHandler {
+	return &ReactionHandler{db: db}
+}
+
+func (h *ReactionHandler) GetReactionTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"types": config.ReactionTypes()})
+}
+
+// AddReaction reacts to a post. Reacting twice with the same type is a no-op.
+func (h *ReactionHandler) AddReaction(c *gin.Context) {
+	postID, reactionType, ok := h.parseReaction(c)
+	if !ok {
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	if !h.postExists(ctx, c, postID) {
+		return
+	}
+
+	reaction := models.Reaction{
+		PostID:    postID,
+		UserID:    userID.(primitive.ObjectID),
+		Type:      reactionType,
+		CreatedAt: time.Now(),
+	}
+	_, err := h.db.Collection("reactions").InsertOne(ctx, reaction)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction"})
+		return
+	}
+
+	// Only count the reaction if this request created it
+	if err == nil {
+		h.adjustCount(ctx, postID, reactionType, 1)
+	}
+
+	h.respond(ctx, c, postID, reaction.UserID)
+}
+
+// RemoveReaction withdraws a reaction. Removing a missing reaction is a no-op.
+func (h *ReactionHandler) RemoveReaction(c *gin.Context) {
+	postID, reactionType, ok := h.parseReaction(c)
+	if !ok {
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	if !h.postExists(ctx, c, postID) {
+		return
+	}
+
+	result, err := h.db.Collection("reactions").DeleteOne(ctx, bson.M{
+		"post_id": postID,
+		"user_id": userID,
+		"type":    reactionType,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reaction"})
+		return
+	}
+	if result.DeletedCount == 1 {
+		h.adjustCount(ctx, postID, reactionType, -1)
+	}
+
+	h.respond(ctx, c, postID, userID.(primitive.ObjectID))
+}
+
+func (h *ReactionHandler) parseReaction(c *gin.Context) (primitive.ObjectID, string, bool) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return postID, "", false
+	}
+
+	reactionType := c.Param("type")
+	for _, t := range config.ReactionTypes() {
+		if t == reactionType {
+			return postID, reactionType, true
+		}
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported reaction type", "types": config.ReactionTypes()})
+	return postID, "", false
+}
+
+func (h *ReactionHandler) postExists(ctx context.Context, c *gin.Context, postID primitive.ObjectID) bool {
+	var post models.Post
+	err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": postID, "status": nil})).Decode(&post)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return false
+	}
+
+	// Posts the caller may not see do not exist as far as they are concerned
+	visible, err := canView(ctx, h.db, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return false
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return false
+	}
+	return true
+}
+
+// adjustCount changes a reaction count. The change time keeps a reconciliation
+// run in progress from overwriting it with counts read before.
+func (h *ReactionHandler) adjustCount(ctx context.Context, postID primitive.ObjectID, reactionType string, delta int) {
+	_, err := h.db.Collection("posts").UpdateOne(ctx, bson.M{"_id": postID}, bson.M{
+		"$inc": bson.M{"reactions." + reactionType: delta},
+		"$set": bson.M{"reactions_changed_at": time.Now()},
+	})
+	if err != nil {
+		log.Printf("Error updating reaction count of post %s: %v", postID.Hex(), err)
+	}
+}
+
+// respond returns the post's current counts and the caller's own reactions
+func (h *ReactionHandler) respond(ctx context.Context, c *gin.Context, postID, userID primitive.ObjectID) {
+	var post models.Post
+	opts := options.FindOne().SetProjection(bson.M{"reactions": 1})
+	if err := h.db.Collection("posts").FindOne(ctx, bson.M{"_id": postID}, opts).Decode(&post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	mine, err := viewerReactions(ctx, h.db, userID, []primitive.ObjectID{postID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	counts := post.Reactions
+	if counts == nil {
+		counts = map[string]int{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reactions":        counts,
+		"viewer_reactions": append([]string{}, mine[postID]...),
+	})
+}
+
+// viewerReactions returns the reaction types the user has left on each of the given posts
+func viewerReactions(ctx context.Context, db *mongo.Database, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	cursor, err := db.Collection("reactions").Find(ctx, bson.M{
+		"user_id": userID,
+		"post_id": bson.M{"$in": postIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reactions []models.Reaction
+	if err := cursor.All(ctx, &reactions); err != nil {
+		return nil, err
+	}
+
+	byPost := make(map[primitive.ObjectID][]string)
+	for _, r := range reactions {
+		byPost[r.PostID] = append(byPost[r.PostID], r.Type)
+	}
+	return byPost, nil
+}
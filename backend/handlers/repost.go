@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
+)
+
+// Repost boosts another post into the caller's timeline. Reposting the same post twice is a no-op.
+func (h *PostHandler) Repost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+
+	original, err := h.findOriginal(ctx, postID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if visible, err := canView(ctx, h.db, c, original); err != nil || !visible {
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if !original.IsPublic() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only public posts can be reposted"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user details"})
+		return
+	}
+
+	now := time.Now()
+	repost := models.Post{
+		UserID:    user.ID,
+		RepostOf:  &original.ID,
+		Author:    user.AsAuthor(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	status := http.StatusCreated
+	result, err := h.db.Collection("posts").InsertOne(ctx, repost)
+	switch {
+	case err == nil:
+		repost.ID = result.InsertedID.(primitive.ObjectID)
+		h.adjustCounter(ctx, original.ID, "repost_count", 1)
+	case mongo.IsDuplicateKeyError(err):
+		// Already reposted, return the existing repost
+		status = http.StatusOK
+		err = h.db.Collection("posts").FindOne(ctx, bson.M{"user_id": user.ID, "repost_of": original.ID}).Decode(&repost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repost"})
+			return
+		}
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create repost"})
+		return
+	}
+
+	if err := h.prepare(ctx, c, &repost); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repost"})
+		return
+	}
+
+	c.JSON(status, repost)
+}
+
+// Unrepost withdraws the caller's repost of a post. Undoing a missing repost is a no-op.
+func (h *PostHandler) Unrepost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := h.removeRepost(context.Background(), userID.(primitive.ObjectID), postID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo repost"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repost removed"})
+}
+
+// removeRepost hard-deletes a user's repost of a post. Reposts carry no content
+// of their own, so there is nothing worth keeping in the trash.
+func (h *PostHandler) removeRepost(ctx context.Context, userID, originalID primitive.ObjectID) error {
+	result, err := h.db.Collection("posts").DeleteOne(ctx, bson.M{"user_id": userID, "repost_of": originalID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 1 {
+		h.adjustCounter(ctx, originalID, "repost_count", -1)
+	}
+	return nil
+}
+
+// findOriginal loads a live, published post, following a repost through to the post it boosts
+func (h *PostHandler) findOriginal(ctx context.Context, postID primitive.ObjectID) (models.Post, error) {
+	var post models.Post
+	if err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": postID, "status": nil})).Decode(&post); err != nil {
+		return post, err
+	}
+	if post.RepostOf == nil {
+		return post, nil
+	}
+
+	var original models.Post
+	err := h.db.Collection("posts").FindOne(ctx, liveFilter(bson.M{"_id": *post.RepostOf, "status": nil})).Decode(&original)
+	return original, err
+}
+
+// prepare completes posts for a response: it embeds the originals of reposts
+// and quote posts, then fills in the viewer-specific fields of all of them.
+func (h *PostHandler) prepare(ctx context.Context, c *gin.Context, posts ...*models.Post) error {
+	embedded, err := h.embedOriginals(ctx, c, posts)
+	if err != nil {
+		return err
+	}
+	posts = append(posts, embedded...)
+	if err := h.markCollapsed(ctx, c, posts); err != nil {
+		return err
+	}
+	return h.annotate(ctx, c, posts...)
+}
+
+// embedOriginals attaches the posts referenced by repost_of and quote_of.
+// Deleted, purged or hidden originals, and those the caller may not see, are
+// replaced by an unavailable stub.
+func (h *PostHandler) embedOriginals(ctx context.Context, c *gin.Context, posts []*models.Post) ([]*models.Post, error) {
+	var ids []primitive.ObjectID
+	for _, p := range posts {
+		if p.RepostOf != nil {
+			ids = append(ids, *p.RepostOf)
+		}
+		if p.QuoteOf != nil {
+			ids = append(ids, *p.QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	filter, err := h.publicPostsFilter(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	filter["_id"] = bson.M{"$in": ids}
+
+	cursor, err := h.db.Collection("posts").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var found []models.Post
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]models.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	var embedded []*models.Post
+	embed := func(id primitive.ObjectID) *models.Post {
+		original, ok := byID[id]
+		if !ok {
+			return &models.Post{ID: id, Unavailable: true}
+		}
+		embedded = append(embedded, &original)
+		return &original
+	}
+	for _, p := range posts {
+		if p.RepostOf != nil {
+			p.Reposted = embed(*p.RepostOf)
+		}
+		if p.QuoteOf != nil {
+			p.Quoted = embed(*p.QuoteOf)
+		}
+	}
+
+	return embedded, nil
+}
+
+// findPostPage pages through posts like findPage and prepares them for the
+// caller. Reposts whose original is gone are dropped, and further posts are
+// fetched in their place, so a page is only short when the listing ends.
+func (h *PostHandler) findPostPage(ctx context.Context, c *gin.Context, filter bson.M, page pageRequest) ([]models.Post, pageInfo, error) {
+	coll := h.db.Collection("posts")
+	kept := []models.Post{}
+	var info pageInfo
+	for first := true; ; first = false {
+		posts, batch, err := findPage(ctx, coll, copyFilter(filter), page, postCursor)
+		if err != nil {
+			return nil, pageInfo{}, err
+		}
+		if len(posts) == 0 {
+			if first {
+				info = batch
+			}
+			break
+		}
+		more := batch.NextCursor != nil
+		if page.After != nil {
+			// findPage always hands out next_cursor when walking forwards
+			more = len(posts) == page.Limit
+		}
+		newest, oldest := postCursor(posts[0]), postCursor(posts[len(posts)-1])
+
+		if err := h.prepare(ctx, c, postPtrs(posts)...); err != nil {
+			return nil, pageInfo{}, err
+		}
+		posts = withoutUnavailableReposts(posts)
+
+		// Walking backwards extends the page with older posts, walking
+		// forwards with newer ones
+		if page.After == nil {
+			kept = append(kept, posts...)
+			if first {
+				info.PrevCursor = batch.PrevCursor
+			}
+			info.NextCursor = batch.NextCursor
+			page.Before = &oldest
+		} else {
+			kept = append(posts, kept...)
+			if first {
+				info.NextCursor = batch.NextCursor
+			}
+			info.PrevCursor = batch.PrevCursor
+			page.After = &newest
+		}
+
+		if !more || len(posts) == page.Limit {
+			break
+		}
+		page.Limit -= len(posts)
+	}
+	return kept, info, nil
+}
+
+// withoutUnavailableReposts drops reposts whose original is gone from a listing.
+// Quote posts stay, since they carry content of their own.
+func withoutUnavailableReposts(posts []models.Post) []models.Post {
+	kept := posts[:0]
+	for _, p := range posts {
+		if p.Reposted != nil && p.Reposted.Unavailable {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept
+}
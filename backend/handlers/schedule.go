@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// GetDrafts lists the caller's drafts and scheduled posts, paginated like the feed
+func (h *PostHandler) GetDrafts(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := liveFilter(bson.M{"user_id": userID, "status": bson.M{"$exists": true}})
+	posts, info, err := findPage(context.Background(), h.db.Collection("posts"), filter, page, postCursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+
+	if err := h.prepare(context.Background(), c, postPtrs(posts)...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"posts": posts, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
+}
+
+// PublishPost publishes one of the caller's drafts or scheduled posts right away
+func (h *PostHandler) PublishPost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+
+	post, err := h.publish(ctx, bson.M{"_id": postID, "user_id": userID, "status": bson.M{"$exists": true}})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish post"})
+		return
+	}
+
+	if err := h.prepare(ctx, c, &post); err != nil {
+		log.Printf("Error preparing post %s: %v", post.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// SchedulePost sets or changes when one of the caller's unpublished posts goes
+// live. A null publish_at turns it back into a draft.
+func (h *PostHandler) SchedulePost(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var input models.SchedulePostInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.PublishAt != nil && !input.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":     models.PostStatusScheduled,
+		"publish_at": input.PublishAt,
+		"updated_at": time.Now(),
+	}}
+	if input.PublishAt == nil {
+		update = bson.M{
+			"$set":   bson.M{"status": models.PostStatusDraft, "updated_at": time.Now()},
+			"$unset": bson.M{"publish_at": ""},
+		}
+	}
+
+	// Matching on status keeps this from reviving a post that was just published.
+	// An ephemeral post must still be live when it is published.
+	filter := liveFilter(bson.M{"_id": postID, "user_id": userID, "status": bson.M{"$exists": true}})
+	if input.PublishAt != nil {
+		filter["$and"] = []bson.M{{"expires_at": bson.M{"$not": bson.M{"$lte": *input.PublishAt}}}}
+	}
+
+	ctx := context.Background()
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	if err := posts.FindOne(ctx, filter).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found, or it expires before publish_at"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule post"})
+		return
+	}
+
+	// The poll window follows the post; drafts get theirs when published
+	if post.Poll != nil && input.PublishAt != nil {
+		update["$set"].(bson.M)["poll.closes_at"] = pollClosesAt(post.Poll, *input.PublishAt)
+	}
+
+	// Only apply the change if nobody changed the post since we read it
+	filter["updated_at"] = post.UpdatedAt
+	err = posts.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Post was modified concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// PublishScheduled publishes every scheduled post whose time has come. Each
+// post is published by a single atomic status change, so overlapping runs on
+// several instances never publish a post twice.
+func (h *PostHandler) PublishScheduled(ctx context.Context) error {
+	for {
+		filter := bson.M{
+			"status":     models.PostStatusScheduled,
+			"publish_at": bson.M{"$lte": time.Now()},
+		}
+		post, err := h.publish(ctx, filter)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Published scheduled post %s", post.ID.Hex())
+	}
+}
+
+// publish makes the oldest unpublished post matching filter live and fires the
+// side effects of creating a post. Its created_at becomes the publication time,
+// so it shows up at the top of feeds rather than where it was first drafted.
+func (h *PostHandler) publish(ctx context.Context, filter bson.M) (models.Post, error) {
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	err := posts.FindOne(ctx, liveFilter(filter), options.FindOne().SetSort(bson.D{{Key: "publish_at", Value: 1}})).Decode(&post)
+	if err != nil {
+		return post, err
+	}
+
+	// A poll runs for its full duration from the moment the post goes live
+	now := time.Now()
+	set := bson.M{"created_at": now, "updated_at": now}
+	if post.Poll != nil {
+		set["poll.closes_at"] = pollClosesAt(post.Poll, now)
+		set["poll.closed"] = false
+	}
+
+	// The filter still applies, so a post published concurrently is not published twice
+	filter = copyFilter(filter)
+	filter["_id"] = post.ID
+	err = posts.FindOneAndUpdate(
+		ctx,
+		liveFilter(filter),
+		bson.M{
+			"$set":   set,
+			"$unset": bson.M{"status": "", "publish_at": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&post)
+	if err != nil {
+		return post, err
+	}
+
+	h.published(ctx, post)
+	return post, nil
+}
+
+// published runs the side effects of a post going live, whether it was created
+// directly or published from a draft or schedule
+func (h *PostHandler) published(ctx context.Context, post models.Post) {
+	h.adjustCounters(ctx, post, 1)
+	if post.IsPublic() {
+		h.recordTagUsage(ctx, post.Tags)
+	}
+	h.publishMentions(ctx, post, nil)
+	if post.PreviewURL != "" && post.LinkPreview == nil {
+		h.previews.AttachAsync(post.ID, post.PreviewURL)
+	}
+}
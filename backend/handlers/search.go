@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
+	"unleashed-space/search"
+)
+
+// maxSearchRounds bounds how many batches of hits one request goes through
+// while skipping results the caller may not see
+const maxSearchRounds = 5
+
+// searchResult is a matching post with its score and highlighted excerpt
+type searchResult struct {
+	Post      models.Post    `json:"post"`
+	Score     float64        `json:"score"`
+	Highlight search.Snippet `json:"highlight"`
+}
+
+// SearchPosts runs a full-text search over posts. Only posts the caller may
+// see are returned, and results are paged with an opaque cursor.
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	q := search.ParseQuery(c.Query("q"))
+	if q.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain at least one word or phrase"})
+		return
+	}
+
+	q.Sort = c.DefaultQuery("sort", search.SortRelevance)
+	if q.Sort != search.SortRelevance && q.Sort != search.SortRecent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be relevance or recent"})
+		return
+	}
+
+	limit := defaultPageLimit
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageLimit)})
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if cur := c.Query("cursor"); cur != "" {
+		n, err := decodeOffsetCursor(cur)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		offset = n
+	}
+
+	var err error
+	if q.Since, err = parseSearchTime(c.Query("since"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be a date (2006-01-02) or RFC 3339 time"})
+		return
+	}
+	if q.Until, err = parseSearchTime(c.Query("until"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be a date (2006-01-02) or RFC 3339 time"})
+		return
+	}
+
+	ctx := context.Background()
+
+	if username := c.Query("author"); username != "" {
+		var author models.User
+		err := h.db.Collection("users").FindOne(ctx, bson.M{"username": username}).Decode(&author)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusOK, gin.H{"results": []searchResult{}, "next_cursor": nil})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+			return
+		}
+		q.AuthorID = &author.ID
+	}
+
+	// The engine only ranks; visibility is applied here when loading the posts
+	visible, err := h.publicPostsFilter(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+
+	results := []searchResult{}
+	exhausted := false
+	for round := 0; round < maxSearchRounds && len(results) < limit && !exhausted; round++ {
+		q.Offset = offset
+		q.Limit = 2 * limit
+		hits, err := h.search.Search(ctx, q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+			return
+		}
+		exhausted = len(hits) < q.Limit
+
+		posts, err := h.postsByID(ctx, visible, hits)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+			return
+		}
+
+		for i, hit := range hits {
+			if len(results) == limit {
+				exhausted = false
+				break
+			}
+			offset = q.Offset + i + 1
+			if post, ok := posts[hit.PostID]; ok {
+				results = append(results, searchResult{Post: post, Score: hit.Score, Highlight: search.Highlight(post.Content, q)})
+			}
+		}
+	}
+
+	ptrs := make([]*models.Post, len(results))
+	for i := range results {
+		ptrs[i] = &results[i].Post
+	}
+	if err := h.prepare(ctx, c, ptrs...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search posts"})
+		return
+	}
+
+	var next *string
+	if !exhausted {
+		cur := encodeOffsetCursor(offset)
+		next = &cur
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "next_cursor": next})
+}
+
+// postsByID loads the posts of hits that also match filter
+func (h *PostHandler) postsByID(ctx context.Context, filter bson.M, hits []search.Hit) (map[primitive.ObjectID]models.Post, error) {
+	byID := make(map[primitive.ObjectID]models.Post, len(hits))
+	if len(hits) == 0 {
+		return byID, nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.PostID)
+	}
+	filter = copyFilter(filter)
+	filter["_id"] = bson.M{"$in": ids}
+
+	cursor, err := h.db.Collection("posts").Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
+
+// parseSearchTime accepts a date or an RFC 3339 time. An empty value is no bound.
+// A date used as an upper bound includes that whole day.
+func parseSearchTime(s string, upper bool) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// encodeOffsetCursor returns the opaque cursor for a position in search results
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(s string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix, offset, ok := strings.Cut(string(raw), ":")
+	if !ok || prefix != "o" {
+		return 0, errors.New("malformed cursor")
+	}
+	n, err := strconv.Atoi(offset)
+	if err != nil || n < 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	return n, nil
+}
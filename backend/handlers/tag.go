@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/content"
+	"unleashed-space/models"
+)
+
+const (
+	defaultTagSuggestions = 10
+	maxTagSuggestions     = 50
+)
+
+func (h *PostHandler) GetTagPosts(c *gin.Context) {
+	tag, ok := content.NormalizeTag(c.Param("tag"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hashtag"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := h.publicPostsFilter(context.Background(), c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+	filter["tags"] = tag
+
+	posts, info, err := findPage(context.Background(), h.db.Collection("posts"), filter, page, postCursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := h.prepare(context.Background(), c, postPtrs(posts)...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tag": tag, "posts": posts, "next_cursor": info.NextCursor, "prev_cursor": info.PrevCursor})
+}
+
+// SuggestTags autocompletes a hashtag prefix, most recently used first
+func (h *PostHandler) SuggestTags(c *gin.Context) {
+	prefix := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "#"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit := defaultTagSuggestions
+	if l := c.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxTagSuggestions {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxTagSuggestions)})
+			return
+		}
+		limit = n
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "last_used_at", Value: -1}, {Key: "use_count", Value: -1}}).
+		SetLimit(int64(limit))
+
+	filter := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	cursor, err := h.db.Collection("tags").Find(context.Background(), filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	tags := []models.Tag{}
+	if err := cursor.All(context.Background(), &tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// recordTagUsage bumps the usage statistics that drive tag autocomplete
+func (h *PostHandler) recordTagUsage(ctx context.Context, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(tags))
+	for _, tag := range tags {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": tag}).
+			SetUpdate(bson.M{
+				"$inc": bson.M{"use_count": 1},
+				"$set": bson.M{"last_used_at": now},
+			}).
+			SetUpsert(true))
+	}
+
+	if _, err := h.db.Collection("tags").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		log.Printf("Error recording tag usage: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+const (
+	defaultThreadDepth = 3
+	maxThreadDepth     = 10
+	// maxThreadNested caps how many nested replies are loaded below one page of replies
+	maxThreadNested = 500
+)
+
+// threadNode is a reply together with the replies nested below it
+type threadNode struct {
+	Post        models.Post   `json:"post"`
+	Replies     []*threadNode `json:"replies"`
+	MoreReplies bool          `json:"more_replies"`
+}
+
+// GetThread returns a post, the chain of posts it replies to, and a page of
+// its direct replies with their own replies nested up to the requested depth.
+func (h *PostHandler) GetThread(c *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	page, err := parsePageRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	depth := defaultThreadDepth
+	if d := c.Query("depth"); d != "" {
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 1 || depth > maxThreadDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be between 1 and " + strconv.Itoa(maxThreadDepth)})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	if err := posts.FindOne(ctx, bson.M{"_id": postID}).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	// Only callers who may see the post learn that it was deleted or expired
+	hidden, err := h.postHidden(ctx, c, post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if post.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Post has been deleted", "deleted_at": post.DeletedAt})
+		return
+	}
+	if post.IsExpired() {
+		c.JSON(http.StatusGone, gin.H{"error": "Post has expired", "expires_at": post.ExpiresAt})
+		return
+	}
+
+	visible, err := h.publicPostsFilter(ctx, c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thread"})
+		return
+	}
+
+	// Ancestors, root first. Missing or hidden ones become placeholders so the chain stays intact.
+	ancestors := []models.Post{}
+	if len(post.Ancestors) > 0 {
+		filter := copyFilter(visible)
+		filter["_id"] = bson.M{"$in": post.Ancestors}
+
+		cursor, err := posts.Find(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thread"})
+			return
+		}
+		var found []models.Post
+		if err := cursor.All(ctx, &found); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode thread"})
+			return
+		}
+
+		byID := make(map[primitive.ObjectID]models.Post, len(found))
+		for _, p := range found {
+			byID[p.ID] = p
+		}
+		for _, id := range post.Ancestors {
+			if p, ok := byID[id]; ok {
+				ancestors = append(ancestors, p)
+			} else {
+				ancestors = append(ancestors, models.Post{ID: id})
+			}
+		}
+	}
+
+	// One page of direct replies
+	replyFilter := copyFilter(visible)
+	replyFilter["in_reply_to"] = postID
+	replies, info, err := findPage(ctx, posts, replyFilter, page, postCursor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch replies"})
+		return
+	}
+
+	nodes := make([]*threadNode, 0, len(replies))
+	byID := make(map[primitive.ObjectID]*threadNode, len(replies))
+	replyIDs := make([]primitive.ObjectID, 0, len(replies))
+	for _, r := range replies {
+		node := &threadNode{Post: r, Replies: []*threadNode{}}
+		nodes = append(nodes, node)
+		byID[r.ID] = node
+		replyIDs = append(replyIDs, r.ID)
+	}
+
+	// Everything below those replies, down to the depth limit, oldest first so parents come before children
+	if depth > 1 && len(replyIDs) > 0 {
+		nestedFilter := copyFilter(visible)
+		nestedFilter["ancestors"] = bson.M{"$in": replyIDs}
+		nestedFilter["depth"] = bson.M{"$lte": post.Depth + depth}
+
+		opts := options.Find().
+			SetSort(bson.D{{Key: "depth", Value: 1}, {Key: "created_at", Value: 1}}).
+			SetLimit(maxThreadNested)
+
+		cursor, err := posts.Find(ctx, nestedFilter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch replies"})
+			return
+		}
+		var nested []models.Post
+		if err := cursor.All(ctx, &nested); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode replies"})
+			return
+		}
+
+		for _, r := range nested {
+			parent, ok := byID[*r.InReplyTo]
+			if !ok {
+				continue
+			}
+			node := &threadNode{Post: r, Replies: []*threadNode{}}
+			parent.Replies = append(parent.Replies, node)
+			byID[r.ID] = node
+		}
+	}
+
+	// Flag nodes with visible replies that were cut off by the depth limit or the nested cap
+	if err := markMoreReplies(ctx, posts, visible, byID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch replies"})
+		return
+	}
+
+	all := []*models.Post{&post}
+	for i := range ancestors {
+		all = append(all, &ancestors[i])
+	}
+	for _, node := range byID {
+		all = append(all, &node.Post)
+	}
+
+	if err := h.prepare(ctx, c, all...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thread"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post":        post,
+		"ancestors":   ancestors,
+		"replies":     nodes,
+		"next_cursor": info.NextCursor,
+		"prev_cursor": info.PrevCursor,
+	})
+}
+
+// markMoreReplies sets MoreReplies on the nodes that have replies the caller may
+// see but that were not loaded. ReplyCount can't be used for this as it also
+// counts replies hidden from the caller.
+func markMoreReplies(ctx context.Context, posts *mongo.Collection, visible bson.M, byID map[primitive.ObjectID]*threadNode) error {
+	if len(byID) == 0 {
+		return nil
+	}
+
+	loaded := make([]primitive.ObjectID, 0, len(byID))
+	for id := range byID {
+		loaded = append(loaded, id)
+	}
+
+	filter := copyFilter(visible)
+	filter["in_reply_to"] = bson.M{"$in": loaded}
+	filter["_id"] = bson.M{"$nin": loaded}
+
+	parents, err := posts.Distinct(ctx, "in_reply_to", filter)
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if id, ok := p.(primitive.ObjectID); ok {
+			if node, ok := byID[id]; ok {
+				node.MoreReplies = true
+			}
+		}
+	}
+	return nil
+}
+
+// copyFilter returns a shallow copy so a shared base filter can be extended per query
+func copyFilter(filter bson.M) bson.M {
+	out := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// restrictedVisibilities are the levels that hide a post from anonymous callers
+var restrictedVisibilities = []string{
+	models.VisibilityFollowers,
+	models.VisibilityMentioned,
+	models.VisibilityPrivate,
+}
+
+// visibilityFilter matches the posts the caller may see. Anonymous callers only
+// see public posts. Signed-in callers also see their own posts, followers-only
+// posts of the users they follow, given in followed, and followers-only or
+// mentioned-only posts that mention them.
+func visibilityFilter(c *gin.Context, followed []primitive.ObjectID) bson.M {
+	public := bson.M{"visibility": bson.M{"$nin": restrictedVisibilities}}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return public
+	}
+	viewer := userID.(primitive.ObjectID)
+
+	return bson.M{"$or": []bson.M{
+		public,
+		{"user_id": viewer},
+		{"visibility": models.VisibilityFollowers, "user_id": bson.M{"$in": followed}},
+		{
+			"visibility":       bson.M{"$in": []string{models.VisibilityFollowers, models.VisibilityMentioned}},
+			"mentions.user_id": viewer,
+		},
+	}}
+}
+
+// viewerFollows returns the IDs of the users the caller follows, or none for
+// anonymous callers
+func viewerFollows(ctx context.Context, db *mongo.Database, c *gin.Context) ([]primitive.ObjectID, error) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil, nil
+	}
+	return followedUserIDs(ctx, db, userID.(primitive.ObjectID))
+}
+
+// canView reports whether the caller may see post. It applies the same rules
+// as visibilityFilter, for single posts that have already been loaded, and
+// also hides unpublished posts from everyone but their author.
+func canView(ctx context.Context, db *mongo.Database, c *gin.Context, post models.Post) (bool, error) {
+	if post.IsPublic() && post.IsPublished() {
+		return true, nil
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return false, nil
+	}
+	viewer := userID.(primitive.ObjectID)
+
+	// Drafts and scheduled posts are only for their author
+	switch {
+	case post.UserID == viewer:
+		return true, nil
+	case !post.IsPublished(), post.Visibility == models.VisibilityPrivate:
+		return false, nil
+	}
+	for _, m := range post.Mentions {
+		if m.UserID == viewer {
+			return true, nil
+		}
+	}
+	if post.Visibility != models.VisibilityFollowers {
+		return false, nil
+	}
+
+	err := db.Collection("follows").FindOne(ctx, bson.M{"follower_id": viewer, "followee_id": post.UserID}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// followedUserIDs returns the IDs of the users that userID follows. Followers
+// visibility depends on the follows collection: without a follow there, a
+// followers post is only visible to its author and the users it mentions.
+func followedUserIDs(ctx context.Context, db *mongo.Database, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"followee_id": 1})
+	cursor, err := db.Collection("follows").Find(ctx, bson.M{"follower_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []models.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, f := range follows {
+		ids = append(ids, f.FolloweeID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// SetContentWarning puts a post behind a content warning or marks it
+// sensitive. Authors can change the warning of their own posts; moderators
+// can apply one to any post, and the author cannot remove it afterwards.
+func (h *PostHandler) SetContentWarning(c *gin.Context) {
+	var input models.ContentWarningInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Warning == "" && !input.Sensitive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give a content_warning or set sensitive"})
+		return
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	if input.Warning != "" {
+		set["content_warning"] = input.Warning
+	} else {
+		unset["content_warning"] = ""
+	}
+	if input.Sensitive {
+		set["sensitive"] = true
+	} else {
+		unset["sensitive"] = ""
+	}
+	h.changeContentWarning(c, bson.M{"$set": set, "$unset": unset})
+}
+
+// ClearContentWarning removes the content warning and sensitive flag of a post
+func (h *PostHandler) ClearContentWarning(c *gin.Context) {
+	h.changeContentWarning(c, bson.M{
+		"$set":   bson.M{},
+		"$unset": bson.M{"content_warning": "", "sensitive": "", "warning_set_by": ""},
+	})
+}
+
+// changeContentWarning applies update, which has both a $set and an $unset,
+// to the post in the URL after checking that the caller may change its warning
+func (h *PostHandler) changeContentWarning(c *gin.Context, update bson.M) {
+	postID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	ctx := context.Background()
+	posts := h.db.Collection("posts")
+
+	var post models.Post
+	if err := posts.FindOne(ctx, liveFilter(bson.M{"_id": postID})).Decode(&post); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+	if post.RepostOf != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reposts cannot have a content warning"})
+		return
+	}
+
+	set, unset := update["$set"].(bson.M), update["$unset"].(bson.M)
+	set["updated_at"] = time.Now()
+
+	// Only apply the change if the warning is still the one we checked
+	filter := liveFilter(bson.M{"_id": postID, "warning_set_by": post.WarningBy})
+	moderator := models.IsModeratorRole(c.GetString("user_role"))
+	switch {
+	case moderator && post.UserID != userID.(primitive.ObjectID):
+		if _, clearing := unset["warning_set_by"]; !clearing {
+			set["warning_set_by"] = userID
+		}
+	case post.UserID != userID.(primitive.ObjectID):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only change the content warning of your own posts"})
+		return
+	case post.WarningBy != nil && !moderator:
+		c.JSON(http.StatusForbidden, gin.H{"error": "A moderator applied this warning; only moderators can change it"})
+		return
+	default:
+		// The author is responsible for their own warning again
+		unset["warning_set_by"] = ""
+	}
+	if len(unset) == 0 {
+		delete(update, "$unset")
+	}
+
+	var updated models.Post
+	err = posts.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Post was modified concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update content warning"})
+		return
+	}
+
+	if err := h.prepare(ctx, c, &updated); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// markCollapsed flags the posts that the caller's preferences hide behind
+// their warning. Anonymous callers get the default preferences, which
+// collapse every warned post.
+func (h *PostHandler) markCollapsed(ctx context.Context, c *gin.Context, posts []*models.Post) error {
+	warned := false
+	for _, p := range posts {
+		warned = warned || p.HasWarning()
+	}
+	if !warned {
+		return nil
+	}
+
+	var prefs models.Preferences
+	if userID, exists := c.Get("user_id"); exists {
+		var user models.User
+		opts := options.FindOne().SetProjection(bson.M{"preferences": 1})
+		err := h.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		prefs = user.Preferences
+	}
+
+	for _, p := range posts {
+		p.Collapsed = prefs.Collapses(*p)
+	}
+	return nil
+}
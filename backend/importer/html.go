@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlToText turns the HTML of an ActivityPub note into the plain text of a
+// post: paragraphs are separated by blank lines, and links become their URL,
+// except for mentions and hashtags, which keep their text.
+func htmlToText(src string) string {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return strings.TrimSpace(src)
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			return
+		case n.Type != html.ElementNode && n.Type != html.DocumentNode:
+			return
+		}
+
+		switch n.Data {
+		case "br":
+			b.WriteString("\n")
+			return
+		case "a":
+			if href := attr(n, "href"); href != "" && !isTagLink(n) {
+				b.WriteString(href)
+				return
+			}
+		case "p":
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return strings.TrimSpace(b.String())
+}
+
+// isTagLink reports whether a link is a mention or hashtag, whose text is
+// kept rather than its URL
+func isTagLink(n *html.Node) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if class == "mention" || class == "hashtag" || class == "u-url" {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
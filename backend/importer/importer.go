@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported archive sources
+const (
+	SourceMastodon = "mastodon" // a Mastodon export, or any ActivityPub outbox.json
+	SourceTwitter  = "twitter"  // a Twitter/X archive
+)
+
+// maxDocumentBytes bounds how much of a single archive file is decompressed,
+// so a small archive cannot expand into an unbounded amount of memory. It is
+// a variable so that tests can lower it.
+var maxDocumentBytes int64 = 256 << 20
+
+var (
+	ErrUnknownSource = errors.New("unknown import source")
+	ErrNoPosts       = errors.New("no posts found in the archive")
+	errTooLarge      = errors.New("archive file is too large")
+)
+
+// Entry is one post found in an archive
+type Entry struct {
+	SourceID   string // the post's ID in the source, used to detect duplicates
+	Content    string // plain text with Markdown-style paragraphs
+	CreatedAt  time.Time
+	InReplyTo  string // SourceID of the post this one replies to, if any
+	Warning    string
+	Sensitive  bool
+	Visibility string
+	Media      []Media
+}
+
+// Media is an attachment of an entry, stored inside the archive
+type Media struct {
+	Path    string
+	AltText string
+}
+
+// Archive holds the entries of a parsed archive, oldest first, and gives
+// access to the media files it contains
+type Archive struct {
+	Entries []Entry
+	Skipped int // boosts, retweets and other entries that are not imported
+
+	files map[string]*zip.File
+}
+
+// Parse reads an archive exported from source. Mastodon exports may be given
+// as the zip archive or as the bare outbox.json.
+func Parse(source string, data []byte) (*Archive, error) {
+	var a *Archive
+	var err error
+	switch source {
+	case SourceMastodon:
+		a, err = parseMastodon(data)
+	case SourceTwitter:
+		a, err = parseTwitter(data)
+	default:
+		return nil, ErrUnknownSource
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Entries) == 0 {
+		return nil, ErrNoPosts
+	}
+
+	// Oldest first, so replies are imported after the posts they answer
+	sort.SliceStable(a.Entries, func(i, j int) bool {
+		return a.Entries[i].CreatedAt.Before(a.Entries[j].CreatedAt)
+	})
+	return a, nil
+}
+
+// ReadMedia returns the contents of a media file of the archive, reading at
+// most limit bytes. It fails if the file is missing or larger than limit.
+func (a *Archive) ReadMedia(name string, limit int64) ([]byte, error) {
+	f, ok := a.files[path.Clean(strings.TrimPrefix(name, "/"))]
+	if !ok {
+		return nil, errors.New("media file not found in archive: " + name)
+	}
+	return readFile(f, limit)
+}
+
+// openZip indexes the files of a zip archive by path
+func openZip(data []byte) (map[string]*zip.File, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[path.Clean(f.Name)] = f
+	}
+	return files, nil
+}
+
+// findFile looks up a file by name, also in a top-level folder, as archives
+// are sometimes re-zipped with their enclosing folder
+func findFile(files map[string]*zip.File, name string) (*zip.File, string) {
+	if f, ok := files[name]; ok {
+		return f, ""
+	}
+	for p, f := range files {
+		if dir, rest, ok := strings.Cut(p, "/"); ok && rest == name {
+			return f, dir + "/"
+		}
+	}
+	return nil, ""
+}
+
+func readFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// isZip reports whether data starts like a zip archive
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// zipArchive builds a zip archive holding the given files
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// limitDocuments lowers maxDocumentBytes for the duration of a test
+func limitDocuments(t *testing.T, limit int64) {
+	saved := maxDocumentBytes
+	maxDocumentBytes = limit
+	t.Cleanup(func() { maxDocumentBytes = saved })
+}
+
+func TestParseUnknownSource(t *testing.T) {
+	if _, err := Parse("myspace", nil); err != ErrUnknownSource {
+		t.Errorf("got %v, want ErrUnknownSource", err)
+	}
+}
+
+func TestParseDocumentLimit(t *testing.T) {
+	limitDocuments(t, 1024)
+	big := `{"orderedItems": [], "padding": "` + strings.Repeat("x", 2048) + `"}`
+
+	cases := map[string]struct {
+		source string
+		data   []byte
+	}{
+		"mastodon": {SourceMastodon, zipArchive(t, map[string]string{"outbox.json": big})},
+		"twitter":  {SourceTwitter, zipArchive(t, map[string]string{"data/tweets.js": "window.YTD.tweets.part0 = [" + strings.Repeat(" ", 2048) + "]"})},
+	}
+	for name, tc := range cases {
+		if _, err := Parse(tc.source, tc.data); err != errTooLarge {
+			t.Errorf("%s: got %v, want errTooLarge", name, err)
+		}
+	}
+}
+
+func TestReadMedia(t *testing.T) {
+	a := &Archive{}
+	var err error
+	if a.files, err = openZip(zipArchive(t, map[string]string{"media/a.png": "12345"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := a.ReadMedia("/media/a.png", 5); err != nil || string(data) != "12345" {
+		t.Errorf("ReadMedia = %q, %v", data, err)
+	}
+	if _, err := a.ReadMedia("media/a.png", 4); err != errTooLarge {
+		t.Errorf("over the limit: got %v, want errTooLarge", err)
+	}
+	if _, err := a.ReadMedia("media/b.png", 5); err == nil {
+		t.Error("missing file: no error")
+	}
+}
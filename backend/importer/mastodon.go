@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"unleashed-space/models"
+)
+
+const publicCollection = "https://www.w3.org/ns/activitystreams#Public"
+
+type outbox struct {
+	OrderedItems []activity `json:"orderedItems"`
+}
+
+type activity struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type note struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	Published  time.Time    `json:"published"`
+	Content    string       `json:"content"`
+	Summary    string       `json:"summary"`
+	Sensitive  bool         `json:"sensitive"`
+	InReplyTo  string       `json:"inReplyTo"`
+	To         []string     `json:"to"`
+	Cc         []string     `json:"cc"`
+	Attachment []attachment `json:"attachment"`
+}
+
+type attachment struct {
+	MediaType string `json:"mediaType"`
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+}
+
+func parseMastodon(data []byte) (*Archive, error) {
+	a := &Archive{}
+	prefix := ""
+	if isZip(data) {
+		files, err := openZip(data)
+		if err != nil {
+			return nil, err
+		}
+		f, dir := findFile(files, "outbox.json")
+		if f == nil {
+			return nil, errors.New("outbox.json not found in the archive")
+		}
+		if data, err = readFile(f, maxDocumentBytes); err != nil {
+			return nil, err
+		}
+		a.files = files
+		prefix = dir
+	}
+
+	var box outbox
+	if err := json.Unmarshal(data, &box); err != nil {
+		return nil, errors.New("invalid outbox.json: " + err.Error())
+	}
+
+	for _, item := range box.OrderedItems {
+		// Boosts and other activities only point at someone else's post
+		var n note
+		if item.Type != "Create" || json.Unmarshal(item.Object, &n) != nil || n.Type != "Note" || n.ID == "" || n.Published.IsZero() {
+			a.Skipped++
+			continue
+		}
+
+		entry := Entry{
+			SourceID:   n.ID,
+			Content:    htmlToText(n.Content),
+			CreatedAt:  n.Published,
+			InReplyTo:  n.InReplyTo,
+			Warning:    strings.TrimSpace(n.Summary),
+			Sensitive:  n.Sensitive,
+			Visibility: noteVisibility(n),
+		}
+		for _, att := range n.Attachment {
+			if !strings.HasPrefix(att.MediaType, "image/") {
+				continue
+			}
+			if u, err := url.Parse(att.URL); err == nil && u.Path != "" {
+				entry.Media = append(entry.Media, Media{Path: prefix + strings.TrimPrefix(u.Path, "/"), AltText: att.Name})
+			}
+		}
+		a.Entries = append(a.Entries, entry)
+	}
+	return a, nil
+}
+
+// noteVisibility maps the addressing of a note onto a post visibility.
+// Direct messages become private, as their remote recipients have no
+// account here.
+func noteVisibility(n note) string {
+	followers := false
+	for _, addr := range append(n.To, n.Cc...) {
+		if addr == publicCollection || addr == "as:Public" || addr == "Public" {
+			return models.VisibilityPublic
+		}
+		if strings.HasSuffix(addr, "/followers") {
+			followers = true
+		}
+	}
+	if followers {
+		return models.VisibilityFollowers
+	}
+	return models.VisibilityPrivate
+}
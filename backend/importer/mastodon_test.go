@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"unleashed-space/models"
+)
+
+const mastodonOutbox = `{
+  "orderedItems": [
+    {
+      "type": "Create",
+      "object": {
+        "id": "https://social.example/users/ann/statuses/2",
+        "type": "Note",
+        "published": "2023-01-02T10:00:00Z",
+        "content": "<p>Second</p><p>with <a href=\"https://social.example/tags/go\" class=\"mention hashtag\">#<span>go</span></a></p>",
+        "summary": " spoilers ",
+        "sensitive": true,
+        "inReplyTo": "https://social.example/users/ann/statuses/1",
+        "to": ["https://social.example/users/ann/followers"],
+        "attachment": [
+          {"mediaType": "image/png", "url": "https://files.social.example/media_attachments/files/1.png", "name": "a cat"},
+          {"mediaType": "video/mp4", "url": "https://files.social.example/media_attachments/files/2.mp4"}
+        ]
+      }
+    },
+    {
+      "type": "Create",
+      "object": {
+        "id": "https://social.example/users/ann/statuses/1",
+        "type": "Note",
+        "published": "2023-01-01T10:00:00Z",
+        "content": "<p>First <a href=\"https://go.dev\">link</a></p>",
+        "to": ["https://www.w3.org/ns/activitystreams#Public"]
+      }
+    },
+    {"type": "Announce", "object": "https://other.example/users/bob/statuses/9"},
+    {"type": "Create", "object": "https://social.example/users/ann/statuses/3"},
+    {"type": "Create", "object": {"id": "https://social.example/users/ann/statuses/4", "type": "Question", "published": "2023-01-03T10:00:00Z"}}
+  ]
+}`
+
+func TestParseMastodon(t *testing.T) {
+	cases := map[string]struct {
+		data      []byte
+		mediaPath string
+	}{
+		"outbox.json":    {[]byte(mastodonOutbox), "media_attachments/files/1.png"},
+		"zip":            {zipArchive(t, map[string]string{"outbox.json": mastodonOutbox}), "media_attachments/files/1.png"},
+		"zip with a dir": {zipArchive(t, map[string]string{"export/outbox.json": mastodonOutbox}), "export/media_attachments/files/1.png"},
+	}
+	for name, tc := range cases {
+		a, err := Parse(SourceMastodon, tc.data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// The boost, the bare link and the poll are skipped
+		if a.Skipped != 3 || len(a.Entries) != 2 {
+			t.Fatalf("%s: %d entries, %d skipped, want 2 and 3", name, len(a.Entries), a.Skipped)
+		}
+
+		first, second := a.Entries[0], a.Entries[1]
+		if first.SourceID != "https://social.example/users/ann/statuses/1" || !first.CreatedAt.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: entries are not oldest first: %+v", name, a.Entries)
+		}
+		if first.Content != "First https://go.dev" || first.Visibility != models.VisibilityPublic || first.InReplyTo != "" {
+			t.Errorf("%s: first entry %+v", name, first)
+		}
+
+		if second.Content != "Second\n\nwith #go" {
+			t.Errorf("%s: content %q", name, second.Content)
+		}
+		if second.InReplyTo != first.SourceID {
+			t.Errorf("%s: in_reply_to %q, want %q", name, second.InReplyTo, first.SourceID)
+		}
+		if second.Visibility != models.VisibilityFollowers || second.Warning != "spoilers" || !second.Sensitive {
+			t.Errorf("%s: second entry %+v", name, second)
+		}
+		if len(second.Media) != 1 || second.Media[0].Path != tc.mediaPath || second.Media[0].AltText != "a cat" {
+			t.Errorf("%s: media %+v, want only the image at %s", name, second.Media, tc.mediaPath)
+		}
+	}
+}
+
+func TestNoteVisibility(t *testing.T) {
+	cases := []struct {
+		to, cc []string
+		want   string
+	}{
+		{[]string{publicCollection}, nil, models.VisibilityPublic},
+		{[]string{"https://social.example/users/ann/followers"}, []string{"as:Public"}, models.VisibilityPublic},
+		{nil, []string{"Public"}, models.VisibilityPublic},
+		{[]string{"https://social.example/users/ann/followers"}, nil, models.VisibilityFollowers},
+		{[]string{"https://other.example/users/bob"}, nil, models.VisibilityPrivate},
+		{nil, nil, models.VisibilityPrivate},
+	}
+	for _, tc := range cases {
+		if got := noteVisibility(note{To: tc.to, Cc: tc.cc}); got != tc.want {
+			t.Errorf("to %v cc %v: got %s, want %s", tc.to, tc.cc, got, tc.want)
+		}
+	}
+}
+
+func TestParseMastodonInvalid(t *testing.T) {
+	cases := map[string][]byte{
+		"not JSON":     []byte("<html>"),
+		"no outbox":    zipArchive(t, map[string]string{"actor.json": "{}"}),
+		"no posts":     []byte(`{"orderedItems": [{"type": "Announce", "object": "x"}]}`),
+		"broken zip":   []byte("PK\x03\x04garbage"),
+		"nested twice": zipArchive(t, map[string]string{"a/b/outbox.json": mastodonOutbox}),
+	}
+	for name, data := range cases {
+		if _, err := Parse(SourceMastodon, data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
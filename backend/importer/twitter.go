@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"path"
+	"strings"
+	"time"
+
+	"unleashed-space/models"
+)
+
+type tweetItem struct {
+	Tweet tweet `json:"tweet"`
+}
+
+type tweet struct {
+	ID                string `json:"id_str"`
+	FullText          string `json:"full_text"`
+	CreatedAt         string `json:"created_at"`
+	InReplyTo         string `json:"in_reply_to_status_id_str"`
+	PossiblySensitive bool   `json:"possibly_sensitive"`
+	Entities          struct {
+		URLs []tweetURL `json:"urls"`
+	} `json:"entities"`
+	ExtendedEntities struct {
+		Media []tweetMedia `json:"media"`
+	} `json:"extended_entities"`
+}
+
+type tweetURL struct {
+	URL         string `json:"url"`
+	ExpandedURL string `json:"expanded_url"`
+}
+
+type tweetMedia struct {
+	URL           string `json:"url"` // the t.co link to the media in the text
+	MediaURLHTTPS string `json:"media_url_https"`
+	Type          string `json:"type"`
+}
+
+func parseTwitter(data []byte) (*Archive, error) {
+	if !isZip(data) {
+		return nil, errors.New("a Twitter archive must be a zip file")
+	}
+	files, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older archives name the file tweet.js
+	f, dir := findFile(files, "data/tweets.js")
+	if f == nil {
+		f, dir = findFile(files, "data/tweet.js")
+	}
+	if f == nil {
+		return nil, errors.New("data/tweets.js not found in the archive")
+	}
+	js, err := readFile(f, maxDocumentBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file assigns the JSON array to a global: window.YTD.tweets.part0 = [...]
+	start := strings.IndexByte(string(js), '[')
+	if start < 0 {
+		return nil, errors.New("invalid tweets.js")
+	}
+	var items []tweetItem
+	if err := json.Unmarshal(js[start:], &items); err != nil {
+		return nil, errors.New("invalid tweets.js: " + err.Error())
+	}
+
+	a := &Archive{files: files}
+	for _, item := range items {
+		t := item.Tweet
+		createdAt, err := time.Parse(time.RubyDate, t.CreatedAt)
+		if err != nil || t.ID == "" || strings.HasPrefix(t.FullText, "RT @") {
+			a.Skipped++
+			continue
+		}
+
+		// Undo the t.co shortening, and drop the links to attached media
+		text := t.FullText
+		for _, u := range t.Entities.URLs {
+			if u.URL != "" && u.ExpandedURL != "" {
+				text = strings.ReplaceAll(text, u.URL, u.ExpandedURL)
+			}
+		}
+		entry := Entry{
+			SourceID:   t.ID,
+			CreatedAt:  createdAt,
+			InReplyTo:  t.InReplyTo,
+			Sensitive:  t.PossiblySensitive,
+			Visibility: models.VisibilityPublic,
+		}
+		for _, m := range t.ExtendedEntities.Media {
+			if m.URL != "" {
+				text = strings.ReplaceAll(text, m.URL, "")
+			}
+			if m.Type == "photo" {
+				entry.Media = append(entry.Media, Media{Path: dir + "data/tweets_media/" + t.ID + "-" + path.Base(m.MediaURLHTTPS)})
+			}
+		}
+		entry.Content = strings.TrimSpace(html.UnescapeString(text))
+
+		a.Entries = append(a.Entries, entry)
+	}
+	return a, nil
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"unleashed-space/models"
+)
+
+const tweetsJS = `window.YTD.tweets.part0 = [
+  {"tweet": {
+    "id_str": "200",
+    "full_text": "@ann agreed &amp; more https://t.co/pic",
+    "created_at": "Tue Jan 03 10:00:00 +0000 2023",
+    "in_reply_to_status_id_str": "100",
+    "possibly_sensitive": true,
+    "extended_entities": {"media": [
+      {"url": "https://t.co/pic", "media_url_https": "https://pbs.twimg.com/media/Abc.jpg", "type": "photo"},
+      {"url": "https://t.co/pic", "media_url_https": "https://pbs.twimg.com/media/Def.mp4", "type": "video"}
+    ]}
+  }},
+  {"tweet": {
+    "id_str": "100",
+    "full_text": "Read https://t.co/xyz",
+    "created_at": "Mon Jan 02 10:00:00 +0000 2023",
+    "entities": {"urls": [{"url": "https://t.co/xyz", "expanded_url": "https://go.dev/blog"}]}
+  }},
+  {"tweet": {"id_str": "300", "full_text": "RT @bob: something", "created_at": "Wed Jan 04 10:00:00 +0000 2023"}},
+  {"tweet": {"id_str": "400", "full_text": "no date", "created_at": "yesterday"}}
+]`
+
+func TestParseTwitter(t *testing.T) {
+	cases := map[string]struct {
+		files     map[string]string
+		mediaPath string
+	}{
+		"tweets.js":      {map[string]string{"data/tweets.js": tweetsJS}, "data/tweets_media/200-Abc.jpg"},
+		"tweet.js":       {map[string]string{"data/tweet.js": tweetsJS}, "data/tweets_media/200-Abc.jpg"},
+		"zip with a dir": {map[string]string{"archive/data/tweets.js": tweetsJS}, "archive/data/tweets_media/200-Abc.jpg"},
+	}
+	for name, tc := range cases {
+		a, err := Parse(SourceTwitter, zipArchive(t, tc.files))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// The retweet and the tweet without a valid date are skipped
+		if a.Skipped != 2 || len(a.Entries) != 2 {
+			t.Fatalf("%s: %d entries, %d skipped, want 2 and 2", name, len(a.Entries), a.Skipped)
+		}
+
+		first, second := a.Entries[0], a.Entries[1]
+		if first.SourceID != "100" || !first.CreatedAt.Equal(time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: entries are not oldest first: %+v", name, a.Entries)
+		}
+		if first.Content != "Read https://go.dev/blog" || first.Visibility != models.VisibilityPublic || len(first.Media) != 0 {
+			t.Errorf("%s: first entry %+v", name, first)
+		}
+
+		if second.Content != "@ann agreed & more" || second.InReplyTo != "100" || !second.Sensitive {
+			t.Errorf("%s: second entry %+v", name, second)
+		}
+		if len(second.Media) != 1 || second.Media[0].Path != tc.mediaPath {
+			t.Errorf("%s: media %+v, want only the photo at %s", name, second.Media, tc.mediaPath)
+		}
+	}
+}
+
+func TestParseTwitterInvalid(t *testing.T) {
+	cases := map[string][]byte{
+		"not a zip":     []byte(tweetsJS),
+		"no tweets.js":  zipArchive(t, map[string]string{"data/account.js": "[]"}),
+		"no array":      zipArchive(t, map[string]string{"data/tweets.js": "window.YTD.tweets.part0 = {}"}),
+		"invalid JSON":  zipArchive(t, map[string]string{"data/tweets.js": "window.YTD.tweets.part0 = [{"}),
+		"only retweets": zipArchive(t, map[string]string{"data/tweets.js": `[{"tweet": {"id_str": "1", "full_text": "RT @bob: x", "created_at": "Mon Jan 02 10:00:00 +0000 2023"}}]`}),
+	}
+	for name, data := range cases {
+		if _, err := Parse(SourceTwitter, data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// SyncPostAuthors copies the current name and username of users whose
+// profile changed onto their posts. Only posts carrying an older profile
+// version are rewritten, so the job is idempotent and an interrupted run is
+// simply resumed by the next one.
+//
+// A user is marked as synced only after a run finds nothing left to update,
+// which also catches posts created from the old profile while a run was
+// under way.
+//
+// Posts rendered before mention links pointed at user IDs link the old
+// username, so those that mention a renamed user are rendered again.
+func SyncPostAuthors(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	cursor, err := users.Find(ctx, bson.M{"author_sync_pending": true},
+		options.Find().SetProjection(bson.M{"name": 1, "username": 1, "profile_version": 1}))
+	if err != nil {
+		return err
+	}
+	var pending []models.User
+	if err := cursor.All(ctx, &pending); err != nil {
+		return err
+	}
+
+	for _, user := range pending {
+		if err := rerenderMentions(ctx, db, user.ID); err != nil {
+			return err
+		}
+
+		result, err := db.Collection("posts").UpdateMany(ctx,
+			bson.M{"user_id": user.ID, "author.version": bson.M{"$not": bson.M{"$gte": user.ProfileVersion}}},
+			bson.M{"$set": bson.M{"author": user.AsAuthor()}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount > 0 {
+			log.Printf("Updated the author of %d posts of user %s", result.ModifiedCount, user.ID.Hex())
+			continue
+		}
+
+		// Leave the flag if the profile changed again in the meantime
+		_, err = users.UpdateOne(ctx,
+			bson.M{"_id": user.ID, "profile_version": user.ProfileVersion},
+			bson.M{"$unset": bson.M{"author_sync_pending": ""}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rerenderMentions renders the content of posts that mention userID again if
+// their stored HTML does not link the user by ID yet
+func rerenderMentions(ctx context.Context, db *mongo.Database, userID primitive.ObjectID) error {
+	posts := db.Collection("posts")
+	cursor, err := posts.Find(ctx,
+		bson.M{
+			"mentions.user_id": userID,
+			"content_html":     bson.M{"$not": primitive.Regex{Pattern: "/profiles/" + userID.Hex()}},
+		},
+		options.Find().SetProjection(bson.M{"content": 1, "mentions": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var post models.Post
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+		// Skip posts edited since they were read; the edit renders them anew
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": post.ID, "content": post.Content}).
+			SetUpdate(bson.M{"$set": bson.M{"content_html": post.RenderContent()}}))
+
+		if len(writes) >= 500 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// storedCount is a count kept on the documents of one collection, derived
+// from the rows of another and updated as rows come and go. The code that
+// updates it must also set changed to the current time, so that a
+// reconciliation run leaves alone counts that moved while it was under way.
+type storedCount struct {
+	source   string         // collection holding the rows
+	pipeline mongo.Pipeline // groups the rows into {_id: document ID, value: count}
+	target   string         // collection holding the counts
+	field    string         // the count
+	changed  string         // time of the last update of the count
+	mark     string         // time of the last reconciliation run that found rows
+	stale    bson.M         // matches documents that carry a count
+	clear    bson.M         // resets the count of documents without rows
+}
+
+// reconcile recomputes the count on every document with rows, then clears it
+// on documents that carry one but had no rows. Documents whose count changed
+// since the run started are skipped either way, as the rows read may already
+// be out of date for them; the next run picks them up. It returns how many
+// documents had rows and how many counts were cleared.
+func (s storedCount) reconcile(ctx context.Context, db *mongo.Database) (int, int64, error) {
+	run := time.Now()
+	unchanged := bson.M{"$not": bson.M{"$gte": run}}
+
+	cursor, err := db.Collection(s.source).Aggregate(ctx, s.pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	target := db.Collection(s.target)
+	counted := 0
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := target.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Value interface{}        `bson:"value"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return 0, 0, err
+		}
+
+		counted++
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": row.ID, s.changed: unchanged}).
+			SetUpdate(bson.M{"$set": bson.M{s.field: row.Value, s.mark: run}}))
+
+		if len(writes) >= 500 {
+			if err := flush(); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, 0, err
+	}
+	if err := flush(); err != nil {
+		return 0, 0, err
+	}
+
+	// Documents that still carry a count but have no rows left
+	filter := bson.M{s.mark: unchanged, s.changed: unchanged}
+	for k, v := range s.stale {
+		filter[k] = v
+	}
+	result, err := target.UpdateMany(ctx, filter, s.clear)
+	if err != nil {
+		return 0, 0, err
+	}
+	return counted, result.ModifiedCount, nil
+}
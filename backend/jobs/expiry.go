@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+// ExpirePosts removes ephemeral posts whose expiry time has passed, together
+// with the replies to them and the dependent data of both. Each post is moved
+// to the trash first, which takes it out of the reply and quote counts exactly
+// once, so an interrupted run is simply finished by the next one.
+//
+// The TTL index on expires_at only removes what this job missed, after a grace
+// period, as it cannot clean up dependent data.
+func ExpirePosts(ctx context.Context, db *mongo.Database) error {
+	posts := db.Collection("posts")
+	now := time.Now()
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "status": 1, "deleted_at": 1, "in_reply_to": 1, "quote_of": 1})
+	cursor, err := posts.Find(ctx, bson.M{"expires_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return err
+	}
+	var expired []models.Post
+	if err := cursor.All(ctx, &expired); err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(expired))
+	for _, post := range expired {
+		ids = append(ids, post.ID)
+	}
+
+	// Replies at any depth go with the post they answer
+	cursor, err = posts.Find(ctx, bson.M{"ancestors": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return err
+	}
+	var replies []models.Post
+	if err := cursor.All(ctx, &replies); err != nil {
+		return err
+	}
+	for _, post := range replies {
+		ids = append(ids, post.ID)
+	}
+
+	for _, post := range append(expired, replies...) {
+		if post.DeletedAt != nil {
+			continue
+		}
+		result, err := posts.UpdateOne(ctx, bson.M{"_id": post.ID, "deleted_at": nil}, bson.M{"$set": bson.M{"deleted_at": now}})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 || !post.IsPublished() {
+			continue
+		}
+		if post.InReplyTo != nil {
+			adjustCount(ctx, posts, *post.InReplyTo, "reply_count")
+		}
+		if post.QuoteOf != nil {
+			adjustCount(ctx, posts, *post.QuoteOf, "quote_count")
+		}
+	}
+
+	_, err = db.Collection("users").UpdateMany(ctx,
+		bson.M{"pinned_posts": bson.M{"$in": ids}},
+		bson.M{"$pull": bson.M{"pinned_posts": bson.M{"$in": ids}}},
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Expiring %d posts and %d replies", len(expired), len(replies))
+	return deletePosts(ctx, db, ids)
+}
+
+// adjustCount takes an expired post out of a counter of the post it refers to
+func adjustCount(ctx context.Context, posts *mongo.Collection, postID primitive.ObjectID, field string) {
+	if _, err := posts.UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$inc": bson.M{field: -1}}); err != nil {
+		log.Printf("Error updating %s of post %s: %v", field, postID.Hex(), err)
+	}
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// followCounts are the follower and following counts stored on users
+var followCounts = []storedCount{
+	followCount("followers_count", "followers", "$followee_id"),
+	followCount("following_count", "following", "$follower_id"),
+}
+
+func followCount(field, prefix, groupBy string) storedCount {
+	return storedCount{
+		source: "follows",
+		pipeline: mongo.Pipeline{
+			{{Key: "$group", Value: bson.M{"_id": groupBy, "value": bson.M{"$sum": 1}}}},
+		},
+		target:  "users",
+		field:   field,
+		changed: prefix + "_changed_at",
+		mark:    prefix + "_reconciled_at",
+		stale:   bson.M{field: bson.M{"$gt": 0}},
+		clear:   bson.M{"$set": bson.M{field: 0}},
+	}
+}
+
+// ReconcileFollowCounts recomputes the follower and following counts stored
+// on users from the follows collection, repairing drift from failed or
+// interrupted updates.
+func ReconcileFollowCounts(ctx context.Context, db *mongo.Database) error {
+	for _, count := range followCounts {
+		counted, cleared, err := count.reconcile(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Reconciled %s for %d users, cleared %d", count.field, counted, cleared)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/config"
+	"unleashed-space/content"
+	"unleashed-space/importer"
+	"unleashed-space/media"
+	"unleashed-space/models"
+	"unleashed-space/storage"
+)
+
+const (
+	// importStall is how long a running import may go without reporting
+	// progress before another run takes it over
+	importStall = 2 * time.Minute
+
+	// importBatch is how many entries are imported between progress reports
+	importBatch = 25
+)
+
+// Outcomes of importing one entry
+const (
+	entryImported = iota
+	entryDuplicate
+	entrySkipped
+)
+
+// RunImports works through pending imports and rollbacks, one job at a time.
+// Progress is saved as it goes, and posts are keyed by their source ID, so a
+// job interrupted by a restart resumes where it stopped without duplicates.
+func RunImports(ctx context.Context, db *mongo.Database, store storage.Storage) error {
+	for {
+		job, err := claimImport(ctx, db)
+		if err != nil || job == nil {
+			return err
+		}
+
+		if job.Status == models.ImportRollingBack {
+			err = rollbackImport(ctx, db, store, job)
+		} else {
+			err = runImport(ctx, db, store, job)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// claimImport takes the oldest job waiting to run or roll back, or one whose
+// runner stopped reporting progress
+func claimImport(ctx context.Context, db *mongo.Database) (*models.ImportJob, error) {
+	stale := time.Now().Add(-importStall)
+	steps := []struct{ from, to string }{
+		{models.ImportPending, models.ImportRunning},
+		{models.ImportRollbackPending, models.ImportRollingBack},
+	}
+
+	for _, step := range steps {
+		var job models.ImportJob
+		err := db.Collection("import_jobs").FindOneAndUpdate(ctx,
+			bson.M{"$or": []bson.M{
+				{"status": step.from},
+				{"status": step.to, "updated_at": bson.M{"$lt": stale}},
+			}},
+			bson.M{"$set": bson.M{"status": step.to, "updated_at": time.Now()}},
+			options.FindOneAndUpdate().
+				SetSort(bson.D{{Key: "created_at", Value: 1}}).
+				SetReturnDocument(options.After),
+		).Decode(&job)
+		if err == nil {
+			return &job, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func runImport(ctx context.Context, db *mongo.Database, store storage.Storage, job *models.ImportJob) error {
+	archive, err := openArchive(ctx, store, job)
+	if err != nil {
+		return finishImport(ctx, db, store, job, models.ImportFailed, "Failed to read the archive: "+err.Error())
+	}
+
+	var user models.User
+	if err := db.Collection("users").FindOne(ctx, bson.M{"_id": job.UserID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return finishImport(ctx, db, store, job, models.ImportFailed, "The importing user no longer exists")
+		}
+		return err
+	}
+
+	if job.Processed == 0 {
+		job.Total = len(archive.Entries)
+		job.Skipped = archive.Skipped
+	}
+	for i := job.Processed; i < len(archive.Entries); i++ {
+		outcome, skippedMedia, err := importEntry(ctx, db, store, archive, job, &user, archive.Entries[i])
+		switch {
+		case err != nil:
+			log.Printf("Error importing entry %s of import %s: %v", archive.Entries[i].SourceID, job.ID.Hex(), err)
+			job.Failed++
+		case outcome == entryImported:
+			job.Imported++
+		case outcome == entryDuplicate:
+			job.Duplicates++
+		default:
+			job.Skipped++
+		}
+		job.SkippedMedia += skippedMedia
+		job.Processed = i + 1
+
+		if job.Processed%importBatch == 0 {
+			current, err := saveProgress(ctx, db, job)
+			if err != nil || !current {
+				return err
+			}
+		}
+	}
+
+	return finishImport(ctx, db, store, job, models.ImportCompleted, "")
+}
+
+func openArchive(ctx context.Context, store storage.Storage, job *models.ImportJob) (*importer.Archive, error) {
+	body, err := store.Open(ctx, job.ArchiveKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return importer.Parse(job.Source, data)
+}
+
+// importEntry creates the post for one archive entry. It returns how many of
+// the entry's media files could not be imported.
+func importEntry(ctx context.Context, db *mongo.Database, store storage.Storage, archive *importer.Archive, job *models.ImportJob, user *models.User, entry importer.Entry) (int, int, error) {
+	if (entry.Content == "" && len(entry.Media) == 0) || utf8.RuneCountInString(entry.Content) > models.MaxContentLength {
+		return entrySkipped, 0, nil
+	}
+
+	posts := db.Collection("posts")
+	source := bson.M{"user_id": user.ID, "import.source": job.Source, "import.source_id": entry.SourceID}
+	if err := posts.FindOne(ctx, source).Err(); err != mongo.ErrNoDocuments {
+		if err == nil {
+			return entryDuplicate, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	post := models.Post{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.ID,
+		Content:    entry.Content,
+		Tags:       content.ExtractHashtags(entry.Content),
+		Author:     user.AsAuthor(),
+		Visibility: entry.Visibility,
+		Warning:    truncate(entry.Warning, models.MaxWarningLength),
+		Sensitive:  entry.Sensitive,
+		CreatedAt:  entry.CreatedAt,
+		UpdatedAt:  entry.CreatedAt,
+		Import:     &models.PostImport{JobID: job.ID, Source: job.Source, SourceID: entry.SourceID},
+	}
+	post.ContentHTML = post.RenderContent()
+
+	// Posts of a suspended user stay hidden like the rest
+	post.AuthorSuspended = user.PostsSuspended
+
+	// Replies stay threaded when the post they answer was imported by the same job
+	var parent models.Post
+	if entry.InReplyTo != "" {
+		err := posts.FindOne(ctx, bson.M{"user_id": user.ID, "import.job_id": job.ID, "import.source_id": entry.InReplyTo, "deleted_at": nil}).Decode(&parent)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, 0, err
+		}
+		if err == nil {
+			post.InReplyTo = &parent.ID
+			post.Ancestors = append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID)
+			post.RootID = &post.Ancestors[0]
+			post.Depth = parent.Depth + 1
+		}
+	}
+
+	uploads, skippedMedia, err := importMedia(ctx, db, store, archive, user.ID, post.ID, entry.Media)
+	if err != nil {
+		releaseMedia(ctx, db, post.ID)
+		return 0, skippedMedia, err
+	}
+	for _, m := range uploads {
+		post.Attachments = append(post.Attachments, models.Attachment{
+			MediaID:      m.ID,
+			Key:          m.Key,
+			ThumbnailKey: m.ThumbnailKey,
+			ContentType:  m.ContentType,
+			Width:        m.Width,
+			Height:       m.Height,
+			AltText:      m.AltText,
+		})
+	}
+
+	if _, err := posts.InsertOne(ctx, post); err != nil {
+		if len(uploads) > 0 {
+			releaseMedia(ctx, db, post.ID)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return entryDuplicate, skippedMedia, nil
+		}
+		return 0, skippedMedia, err
+	}
+	if post.InReplyTo != nil {
+		if _, err := posts.UpdateOne(ctx, bson.M{"_id": parent.ID}, bson.M{"$inc": bson.M{"reply_count": 1}}); err != nil {
+			log.Printf("Error updating reply_count of post %s: %v", parent.ID.Hex(), err)
+		}
+	}
+	return entryImported, skippedMedia, nil
+}
+
+// importMedia stores the images of an entry as uploads attached to the post
+// about to be created, so that CleanupMedia never takes them for abandoned
+// ones. Files that are missing, too large or not JPEG, PNG or GIF images are
+// left out.
+func importMedia(ctx context.Context, db *mongo.Database, store storage.Storage, archive *importer.Archive, userID, postID primitive.ObjectID, files []importer.Media) ([]models.Media, int, error) {
+	var uploads []models.Media
+	skipped := 0
+	for _, f := range files {
+		if len(uploads) == models.MaxAttachments {
+			skipped++
+			continue
+		}
+
+		data, err := archive.ReadMedia(f.Path, config.MediaMaxBytes())
+		if err != nil {
+			skipped++
+			continue
+		}
+		img, err := media.Process(data)
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		id := primitive.NewObjectID()
+		m := models.Media{
+			ID:           id,
+			UserID:       userID,
+			PostID:       &postID,
+			Key:          id.Hex() + "." + img.Ext,
+			ThumbnailKey: id.Hex() + "_thumb." + img.ThumbExt,
+			ContentType:  img.ContentType,
+			Width:        img.Width,
+			Height:       img.Height,
+			Size:         len(img.Data),
+			AltText:      f.AltText,
+			CreatedAt:    time.Now(),
+		}
+		if err := store.Put(ctx, m.Key, img.Data, img.ContentType); err != nil {
+			return uploads, skipped, err
+		}
+		if err := store.Put(ctx, m.ThumbnailKey, img.Thumbnail, img.ThumbType); err != nil {
+			return uploads, skipped, err
+		}
+		if _, err := db.Collection("media").InsertOne(ctx, m); err != nil {
+			return uploads, skipped, err
+		}
+		uploads = append(uploads, m)
+	}
+	return uploads, skipped, nil
+}
+
+// releaseMedia detaches the uploads made for a post that was not created, so
+// that CleanupMedia removes them
+func releaseMedia(ctx context.Context, db *mongo.Database, postID primitive.ObjectID) {
+	_, err := db.Collection("media").UpdateMany(ctx, bson.M{"post_id": postID}, bson.M{"$unset": bson.M{"post_id": ""}})
+	if err != nil {
+		log.Printf("Error releasing media of post %s: %v", postID.Hex(), err)
+	}
+}
+
+// saveProgress records the counters of a job. It reports false if the job is
+// no longer in the state this run claimed it in, in which case the run stops.
+func saveProgress(ctx context.Context, db *mongo.Database, job *models.ImportJob) (bool, error) {
+	job.UpdatedAt = time.Now()
+	result, err := db.Collection("import_jobs").UpdateOne(ctx,
+		bson.M{"_id": job.ID, "status": job.Status},
+		bson.M{"$set": bson.M{
+			"total":         job.Total,
+			"processed":     job.Processed,
+			"imported":      job.Imported,
+			"duplicates":    job.Duplicates,
+			"skipped":       job.Skipped,
+			"failed":        job.Failed,
+			"skipped_media": job.SkippedMedia,
+			"updated_at":    job.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// finishImport saves the final state of a job and removes its archive, which
+// is no longer needed once the job completed, failed or was rolled back
+func finishImport(ctx context.Context, db *mongo.Database, store storage.Storage, job *models.ImportJob, status, message string) error {
+	if current, err := saveProgress(ctx, db, job); err != nil || !current {
+		return err
+	}
+
+	now := time.Now()
+	_, err := db.Collection("import_jobs").UpdateOne(ctx,
+		bson.M{"_id": job.ID, "status": job.Status},
+		bson.M{
+			"$set":   bson.M{"status": status, "error": message, "finished_at": now, "updated_at": now},
+			"$unset": bson.M{"archive_key": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if job.ArchiveKey != "" {
+		if err := store.Delete(ctx, job.ArchiveKey); err != nil && err != storage.ErrNotFound {
+			log.Printf("Error deleting import archive %s: %v", job.ArchiveKey, err)
+		}
+	}
+	log.Printf("Import %s %s: %d imported, %d duplicates, %d skipped, %d failed", job.ID.Hex(), status, job.Imported, job.Duplicates, job.Skipped, job.Failed)
+	return nil
+}
+
+// rollbackImport removes every post an import created, with their dependent
+// data, in batches so progress survives an interruption
+func rollbackImport(ctx context.Context, db *mongo.Database, store storage.Storage, job *models.ImportJob) error {
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(500)
+	for {
+		cursor, err := db.Collection("posts").Find(ctx, bson.M{"import.job_id": job.ID}, opts)
+		if err != nil {
+			return err
+		}
+		var batch []models.Post
+		if err := cursor.All(ctx, &batch); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(batch))
+		for _, p := range batch {
+			ids = append(ids, p.ID)
+		}
+		_, err = db.Collection("users").UpdateOne(ctx,
+			bson.M{"_id": job.UserID},
+			bson.M{"$pull": bson.M{"pinned_posts": bson.M{"$in": ids}}},
+		)
+		if err != nil {
+			return err
+		}
+		if err := deletePosts(ctx, db, ids); err != nil {
+			return err
+		}
+
+		if current, err := saveProgress(ctx, db, job); err != nil || !current {
+			return err
+		}
+	}
+
+	return finishImport(ctx, db, store, job, models.ImportRolledBack, "")
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// instanceID identifies this process as the holder of job leases
+var instanceID = func() string {
+	host, _ := os.Hostname()
+	return host + "-" + primitive.NewObjectID().Hex()
+}()
+
+// WithLease wraps fn so that, across all server instances sharing the
+// database, only the holder of the named lease runs it. The holder renews the
+// lease on every run; if it stops, another instance takes over once ttl has
+// passed since the last renewal. fn should still tolerate the rare overlap of
+// a run that outlasts ttl.
+func WithLease(db *mongo.Database, name string, ttl time.Duration, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		_, err := db.Collection("job_leases").UpdateOne(
+			ctx,
+			bson.M{
+				"_id": name,
+				"$or": []bson.M{
+					{"holder": instanceID},
+					{"expires_at": bson.M{"$lte": now}},
+				},
+			},
+			bson.M{"$set": bson.M{"holder": instanceID, "expires_at": now.Add(ttl)}},
+			options.Update().SetUpsert(true),
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another instance holds the lease
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
+	"unleashed-space/storage"
+)
+
+// CleanupMedia removes uploads that are not attached to any post once they are
+// older than grace: abandoned uploads and media of purged posts alike.
+func CleanupMedia(ctx context.Context, db *mongo.Database, store storage.Storage, grace time.Duration) error {
+	filter := bson.M{
+		"post_id":    nil,
+		"created_at": bson.M{"$lt": time.Now().Add(-grace)},
+	}
+
+	cursor, err := db.Collection("media").Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	removed := 0
+	for cursor.Next(ctx) {
+		var m models.Media
+		if err := cursor.Decode(&m); err != nil {
+			return err
+		}
+
+		// Files first, so a failure leaves the record to retry on the next run
+		if err := store.Delete(ctx, m.Key); err != nil {
+			log.Printf("Error deleting media %s: %v", m.Key, err)
+			continue
+		}
+		if err := store.Delete(ctx, m.ThumbnailKey); err != nil {
+			log.Printf("Error deleting media %s: %v", m.ThumbnailKey, err)
+			continue
+		}
+		if _, err := db.Collection("media").DeleteOne(ctx, bson.M{"_id": m.ID, "post_id": nil}); err != nil {
+			return err
+		}
+		removed++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if removed > 0 {
+		log.Printf("Removed %d unattached media files", removed)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/events"
+	"unleashed-space/models"
+)
+
+// ClosePolls marks polls whose closing time has passed as closed and tells
+// their author and voters that the results are final. Each poll is closed by
+// a conditional update, so overlapping runs notify only once.
+func ClosePolls(ctx context.Context, db *mongo.Database) error {
+	posts := db.Collection("posts")
+	filter := bson.M{
+		"poll.closed":    false,
+		"poll.closes_at": bson.M{"$lte": time.Now()},
+		"deleted_at":     nil,
+		"status":         nil,
+	}
+
+	cursor, err := posts.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "user_id": 1}))
+	if err != nil {
+		return err
+	}
+	var due []models.Post
+	if err := cursor.All(ctx, &due); err != nil {
+		return err
+	}
+
+	for _, post := range due {
+		result, err := posts.UpdateOne(ctx, bson.M{"_id": post.ID, "poll.closed": false}, bson.M{"$set": bson.M{"poll.closed": true}})
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		voters, err := db.Collection("poll_votes").Distinct(ctx, "user_id", bson.M{"post_id": post.ID})
+		if err != nil {
+			log.Printf("Error listing voters of poll %s: %v", post.ID.Hex(), err)
+		}
+
+		evts := []models.Event{{Type: models.EventPollClosed, UserID: post.UserID, ActorID: post.UserID, PostID: post.ID}}
+		for _, v := range voters {
+			if voter, ok := v.(primitive.ObjectID); ok && voter != post.UserID {
+				evts = append(evts, models.Event{Type: models.EventPollClosed, UserID: voter, ActorID: post.UserID, PostID: post.ID})
+			}
+		}
+		if err := events.Publish(ctx, db, evts...); err != nil {
+			log.Printf("Error publishing poll closed events for post %s: %v", post.ID.Hex(), err)
+		}
+	}
+
+	if len(due) > 0 {
+		log.Printf("Closed %d polls", len(due))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// postDependents are the collections holding per-post data, keyed by post_id
+var postDependents = []string{"post_revisions", "reactions", "poll_votes", "bookmarks"}
+
+// PurgeDeletedPosts permanently removes posts that have been in the trash
+// longer than the retention window, together with their dependent data.
+func PurgeDeletedPosts(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+	filter := bson.M{"deleted_at": bson.M{"$lte": cutoff}}
+
+	cursor, err := db.Collection("posts").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+
+	var posts []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &posts); err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+
+	return deletePosts(ctx, db, ids)
+}
+
+// deletePosts hard-deletes the given posts and everything that references them.
+// Dependents go first so an interrupted run is simply retried on the next tick.
+func deletePosts(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) error {
+	for _, name := range postDependents {
+		if _, err := db.Collection(name).DeleteMany(ctx, bson.M{"post_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+	}
+
+	// Detached media is removed from storage by CleanupMedia
+	_, err := db.Collection("media").UpdateMany(ctx, bson.M{"post_id": bson.M{"$in": ids}}, bson.M{"$unset": bson.M{"post_id": ""}})
+	if err != nil {
+		return err
+	}
+
+	// Reposts only point at the original, so they go with it
+	if _, err := db.Collection("posts").DeleteMany(ctx, bson.M{"repost_of": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+
+	result, err := db.Collection("posts").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Purged %d posts", result.DeletedCount)
+	return nil
+}
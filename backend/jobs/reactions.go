@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// reactionCounts are the per-type reaction counts stored on posts
+var reactionCounts = storedCount{
+	source: "reactions",
+	pipeline: mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"post_id": "$post_id", "type": "$type"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$_id.post_id",
+			"counts": bson.M{"$push": bson.M{"k": "$_id.type", "v": "$count"}},
+		}}},
+		{{Key: "$project", Value: bson.M{"value": bson.M{"$arrayToObject": "$counts"}}}},
+	},
+	target:  "posts",
+	field:   "reactions",
+	changed: "reactions_changed_at",
+	mark:    "reactions_reconciled_at",
+	stale:   bson.M{"reactions": bson.M{"$exists": true}},
+	clear:   bson.M{"$unset": bson.M{"reactions": "", "reactions_reconciled_at": ""}},
+}
+
+// ReconcileReactionCounts recomputes the reaction counts stored on posts from
+// the reactions collection, repairing drift from failed or interrupted updates.
+func ReconcileReactionCounts(ctx context.Context, db *mongo.Database) error {
+	counted, cleared, err := reactionCounts.reconcile(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Reconciled reaction counts for %d posts, cleared %d", counted, cleared)
+	return nil
+}
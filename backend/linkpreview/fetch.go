@@ -0,0 +1,262 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"unleashed-space/models"
+)
+
+const (
+	maxRedirects   = 3
+	maxBodyBytes   = 512 << 10
+	fetchTimeout   = 8 * time.Second
+	maxTitle       = 300
+	maxDescription = 1000
+	userAgent      = "KomunalBot/1.0 (link preview)"
+)
+
+// ErrForbiddenAddress is returned when a URL resolves to an address we refuse to contact
+var ErrForbiddenAddress = errors.New("linkpreview: address not allowed")
+
+// blockedPrefixes are non-public ranges not already covered by the net.IP helpers
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, can map onto private IPv4
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// Fetcher downloads pages for previews while guarding against SSRF:
+// only http(s) on standard ports, only public IP addresses (checked at connect
+// time, after DNS resolution and on every redirect), few redirects, and hard
+// limits on time and size.
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher() *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    5 * time.Second,
+		ResponseHeaderTimeout:  5 * time.Second,
+		MaxResponseHeaderBytes: 64 << 10,
+		DisableKeepAlives:      true,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("linkpreview: too many redirects")
+			}
+			return checkURL(req.URL)
+		},
+	}
+
+	return &Fetcher{client: client}
+}
+
+// Fetch returns the preview metadata of the page at rawURL, or nil if the page has none
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*models.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("linkpreview: %s returned %s", rawURL, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, nil
+	}
+
+	preview := parseHead(io.LimitReader(resp.Body, maxBodyBytes), resp.Request.URL)
+	if preview != nil {
+		preview.URL = rawURL
+	}
+	return preview, nil
+}
+
+// checkURL allows only http(s) URLs without credentials on the default ports
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("linkpreview: scheme %q not allowed", u.Scheme)
+	}
+	if u.User != nil || u.Hostname() == "" {
+		return ErrForbiddenAddress
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// checkAddress is run on the resolved ip:port right before connecting
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ErrForbiddenAddress
+	}
+	if !isPublic(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHead reads OpenGraph, Twitter card and plain HTML metadata from the document head
+func parseHead(r io.Reader, base *url.URL) *models.LinkPreview {
+	meta := make(map[string]string)
+	var title string
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return buildPreview(meta, title, base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "body":
+				return buildPreview(meta, title, base)
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "meta":
+				var key, value string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						value = string(v)
+					}
+				}
+				if key != "" && meta[key] == "" {
+					meta[key] = strings.TrimSpace(value)
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "head":
+				return buildPreview(meta, title, base)
+			case "title":
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		}
+	}
+}
+
+func buildPreview(meta map[string]string, title string, base *url.URL) *models.LinkPreview {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := meta[k]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	preview := &models.LinkPreview{
+		Title:       truncate(first("og:title", "twitter:title"), maxTitle),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescription),
+		SiteName:    truncate(first("og:site_name"), maxTitle),
+	}
+	if preview.Title == "" {
+		preview.Title = truncate(title, maxTitle)
+	}
+	if preview.Title == "" && preview.Description == "" {
+		return nil
+	}
+
+	// Only keep absolute http(s) image URLs; clients load them directly
+	if img := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); img != "" {
+		if ref, err := url.Parse(img); err == nil {
+			abs := base.ResolveReference(ref)
+			if abs.Scheme == "http" || abs.Scheme == "https" {
+				preview.ImageURL = abs.String()
+			}
+		}
+	}
+	return preview
+}
+
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(strings.Join(strings.Fields(s), " "), "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,119 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:4700::1111":        true,
+		"127.0.0.1":              false, // loopback
+		"127.255.255.254":        false,
+		"::1":                    false,
+		"10.1.2.3":               false, // RFC 1918
+		"172.16.0.1":             false,
+		"172.31.255.255":         false,
+		"192.168.1.1":            false,
+		"fd00::1":                false, // unique local
+		"169.254.169.254":        false, // link-local, cloud metadata
+		"fd00:ec2::254":          false, // AWS IPv6 metadata
+		"100.100.100.200":        false, // carrier-grade NAT, Alibaba metadata
+		"fe80::1":                false,
+		"::ffff:127.0.0.1":       false, // IPv4-mapped IPv6
+		"::ffff:10.0.0.1":        false,
+		"::ffff:169.254.169.254": false,
+		"64:ff9b::a00:1":         false, // NAT64 of 10.0.0.1
+		"0.0.0.0":                false,
+		"::":                     false,
+		"224.0.0.1":              false, // multicast
+		"255.255.255.255":        false,
+		"192.0.2.1":              false, // documentation
+	}
+	for addr, want := range cases {
+		if got := isPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	cases := map[string]error{
+		"93.184.216.34:443":      nil,
+		"[2606:4700::1111]:80":   nil,
+		"127.0.0.1:80":           ErrForbiddenAddress,
+		"[::ffff:127.0.0.1]:443": ErrForbiddenAddress,
+		"169.254.169.254:80":     ErrForbiddenAddress,
+		"localhost:80":           ErrForbiddenAddress, // not resolved yet
+	}
+	for address, want := range cases {
+		if got := checkAddress(address); got != want {
+			t.Errorf("checkAddress(%s) = %v, want %v", address, got, want)
+		}
+	}
+	if checkAddress("no-port") == nil {
+		t.Error("checkAddress without a port: no error")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://example.com/a":       true,
+		"http://example.com:80/":      true,
+		"https://example.com:443/":    true,
+		"https://example.com:8443/":   false,
+		"ftp://example.com/":          false,
+		"file:///etc/passwd":          false,
+		"https://user:pw@example.com": false,
+		"https:///path":               false,
+	}
+	for raw, ok := range cases {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkURL(u); (err == nil) != ok {
+			t.Errorf("checkURL(%s) = %v, want allowed %v", raw, err, ok)
+		}
+	}
+}
+
+// The address check runs when connecting, so names that resolve to a
+// private address are refused as well
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	f := NewFetcher()
+	for _, raw := range []string{"http://127.0.0.1/", "http://localhost/", "http://[::1]/", "http://169.254.169.254/latest/meta-data/"} {
+		if _, err := f.Fetch(context.Background(), raw); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Fetch(%s) = %v, want ErrForbiddenAddress", raw, err)
+		}
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	f := NewFetcher()
+	req := func(raw string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, raw, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	via := []*http.Request{req("https://example.com/1"), req("https://example.com/2")}
+
+	if err := f.client.CheckRedirect(req("https://example.com/3"), via); err != nil {
+		t.Errorf("redirect %d: %v", len(via)+1, err)
+	}
+	if err := f.client.CheckRedirect(req("https://example.com/4"), append(via, req("https://example.com/3"))); err == nil {
+		t.Errorf("redirect %d: no error", maxRedirects+1)
+	}
+	for _, raw := range []string{"ftp://example.com/", "http://example.com:8080/", "https://user@example.com/"} {
+		if err := f.client.CheckRedirect(req(raw), via[:1]); err == nil {
+			t.Errorf("redirect to %s: no error", raw)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package linkpreview
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+const (
+	// maxConcurrentFetches bounds outgoing preview requests across all posts
+	maxConcurrentFetches = 4
+	// maxQueuedFetches bounds the posts waiting for a preview. Further posts
+	// get none rather than piling up behind a slow site.
+	maxQueuedFetches = 256
+	cacheTTL         = 24 * time.Hour
+	failureCacheTTL  = time.Hour
+)
+
+// attachment is a post waiting for the preview of its URL
+type attachment struct {
+	postID primitive.ObjectID
+	url    string
+}
+
+// cacheEntry is the cached result of fetching one URL. A nil Preview means
+// the page had no usable metadata or could not be fetched.
+type cacheEntry struct {
+	URL       string              `bson:"_id"`
+	Preview   *models.LinkPreview `bson:"preview,omitempty"`
+	Error     string              `bson:"error,omitempty"`
+	FetchedAt time.Time           `bson:"fetched_at"`
+	ExpiresAt time.Time           `bson:"expires_at"`
+}
+
+// Service fetches link previews in the background and attaches them to posts.
+// A fixed set of workers takes posts from a bounded queue.
+type Service struct {
+	db      *mongo.Database
+	fetcher *Fetcher
+	queue   chan attachment
+}
+
+func NewService(db *mongo.Database) *Service {
+	s := &Service{
+		db:      db,
+		fetcher: NewFetcher(),
+		queue:   make(chan attachment, maxQueuedFetches),
+	}
+	for i := 0; i < maxConcurrentFetches; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// Cached returns the cached preview for url. ok is false when url has not been
+// fetched recently; a nil preview with ok true means the URL has no preview.
+func (s *Service) Cached(ctx context.Context, url string) (preview *models.LinkPreview, ok bool) {
+	var entry cacheEntry
+	err := s.db.Collection("link_previews").FindOne(ctx, bson.M{
+		"_id":        url,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&entry)
+	if err != nil {
+		return nil, false
+	}
+	return entry.Preview, true
+}
+
+// AttachAsync fetches the preview of url in the background and stores it on
+// the post, unless the post has since been edited to point at another URL.
+// When the queue is full the post goes without a preview.
+func (s *Service) AttachAsync(postID primitive.ObjectID, url string) {
+	select {
+	case s.queue <- attachment{postID: postID, url: url}:
+	default:
+		log.Printf("Link preview queue is full, skipping the preview of post %s", postID.Hex())
+	}
+}
+
+func (s *Service) work() {
+	for a := range s.queue {
+		s.attach(a)
+	}
+}
+
+func (s *Service) attach(a attachment) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*fetchTimeout)
+	defer cancel()
+
+	preview, ok := s.Cached(ctx, a.url)
+	if !ok {
+		preview = s.fetch(ctx, a.url)
+	}
+	if preview == nil {
+		return
+	}
+
+	_, err := s.db.Collection("posts").UpdateOne(ctx,
+		bson.M{"_id": a.postID, "preview_url": a.url},
+		bson.M{"$set": bson.M{"link_preview": preview}},
+	)
+	if err != nil {
+		log.Printf("Error attaching link preview to post %s: %v", a.postID.Hex(), err)
+	}
+}
+
+// fetch downloads a preview and caches the outcome, failures included
+func (s *Service) fetch(ctx context.Context, url string) *models.LinkPreview {
+	now := time.Now()
+	entry := cacheEntry{URL: url, FetchedAt: now, ExpiresAt: now.Add(cacheTTL)}
+
+	preview, err := s.fetcher.Fetch(ctx, url)
+	if err != nil {
+		log.Printf("Link preview for %s failed: %v", url, err)
+		entry.Error = err.Error()
+		entry.ExpiresAt = now.Add(failureCacheTTL)
+	}
+	entry.Preview = preview
+
+	_, err = s.db.Collection("link_previews").ReplaceOne(ctx, bson.M{"_id": url}, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Error caching link preview for %s: %v", url, err)
+	}
+	return preview
+}
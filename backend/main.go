@@ -14,8 +14,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 
+	"unleashed-space/config"
 	"unleashed-space/handlers"
+	"unleashed-space/jobs"
 	"unleashed-space/middleware"
+	"unleashed-space/models"
+	"unleashed-space/search"
+	"unleashed-space/storage"
 )
 
 func initMongoDB() (*mongo.Client, *mongo.Database, error) {
@@ -120,6 +125,10 @@ func ensureCollections(ctx context.Context, db *mongo.Database) error {
 			Keys:    bson.D{{Key: "username", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			Keys:    bson.D{{Key: "author_sync_pending", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	})
 	if err != nil {
 		return err
@@ -138,9 +147,139 @@ func ensureCollections(ctx context.Context, db *mongo.Database) error {
 		}
 	}
 
+	// Indexes for the remaining collections
+	for name, indexModels := range collectionIndexes {
+		log.Printf("Creating indexes for %s collection...", name)
+		if _, err := db.Collection(name).Indexes().CreateMany(ctx, indexModels); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// collectionIndexes lists the indexes created at startup for every collection except users
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"posts": {
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{Keys: bson.D{{Key: "in_reply_to", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}, {Key: "depth", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "mentions.user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "repost_of", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"repost_of": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "repost_of", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "publish_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "poll.closes_at", Value: 1}},
+			Options: options.Index().
+				SetPartialFilterExpression(bson.M{"poll.closed": false}),
+		},
+		{
+			Keys:    bson.D{{Key: "content", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
+		// A backstop for the expiry job, which also removes dependent data
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(3600).SetSparse(true),
+		},
+		// Makes a resumed import skip the posts it already created
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "import.source", Value: 1}, {Key: "import.source_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"import": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "import.job_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	},
+	"reactions": {
+		{
+			Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "type", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}}},
+	},
+	"tags": {
+		{Keys: bson.D{{Key: "last_used_at", Value: -1}}},
+	},
+	"link_previews": {
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"media": {
+		{Keys: bson.D{{Key: "post_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+	},
+	"bookmarks": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "folder_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "post_id", Value: 1}}},
+	},
+	"bookmark_folders": {
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	},
+	"idempotency_keys": {
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	},
+	"follows": {
+		{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "followee_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+	},
+	"import_jobs": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"events": {
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "claimed_at", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"poll_votes": {
+		{
+			Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}}},
+	},
+	"post_revisions": {
+		{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "replaced_at", Value: -1}}},
+	},
+}
+
 func main() {
 	// Set up logging
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
@@ -163,10 +302,52 @@ func main() {
 
 	log.Println("Successfully connected to MongoDB")
 
+	// Initialize media storage
+	store, err := storage.New(config.Storage())
+	if err != nil {
+		log.Fatalf("Failed to initialize media storage: %v", err)
+	}
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db)
 	profileHandler := handlers.NewProfileHandler(db)
-	postHandler := handlers.NewPostHandler(db)
+	postHandler := handlers.NewPostHandler(db, search.NewMongoEngine(db))
+	adminHandler := handlers.NewAdminHandler(db)
+	reactionHandler := handlers.NewReactionHandler(db)
+	mediaHandler := handlers.NewMediaHandler(db, store)
+	importHandler := handlers.NewImportHandler(db, store)
+	followHandler := handlers.NewFollowHandler(db)
+
+	// Start background jobs
+	go jobs.Every(context.Background(), "purge deleted posts", time.Hour, func(ctx context.Context) error {
+		return jobs.PurgeDeletedPosts(ctx, db, config.TrashRetention())
+	})
+	go jobs.Every(context.Background(), "reconcile reaction counts", 6*time.Hour, func(ctx context.Context) error {
+		return jobs.ReconcileReactionCounts(ctx, db)
+	})
+	go jobs.Every(context.Background(), "reconcile follow counts", 6*time.Hour, func(ctx context.Context) error {
+		return jobs.ReconcileFollowCounts(ctx, db)
+	})
+	go jobs.Every(context.Background(), "clean up media", time.Hour, func(ctx context.Context) error {
+		return jobs.CleanupMedia(ctx, db, store, 24*time.Hour)
+	})
+	go jobs.Every(context.Background(), "publish scheduled posts", 30*time.Second,
+		jobs.WithLease(db, "publish scheduled posts", 2*time.Minute, postHandler.PublishScheduled))
+	go jobs.Every(context.Background(), "close polls", time.Minute, func(ctx context.Context) error {
+		return jobs.ClosePolls(ctx, db)
+	})
+	go jobs.Every(context.Background(), "expire posts", time.Minute, func(ctx context.Context) error {
+		return jobs.ExpirePosts(ctx, db)
+	})
+	go jobs.Every(context.Background(), "sync post authors", 15*time.Second,
+		jobs.WithLease(db, "sync post authors", time.Minute, func(ctx context.Context) error {
+			return jobs.SyncPostAuthors(ctx, db)
+		}))
+	go jobs.Every(context.Background(), "sync suspended posts", time.Minute,
+		jobs.WithLease(db, "sync suspended posts", time.Minute, adminHandler.SyncSuspendedPosts))
+	go jobs.Every(context.Background(), "run imports", 10*time.Second, func(ctx context.Context) error {
+		return jobs.RunImports(ctx, db, store)
+	})
 
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
@@ -196,6 +377,9 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Uploaded media, served to those who may see the post it is attached to
+	router.GET("/media/:key", middleware.OptionalAuthMiddleware(db), mediaHandler.Serve)
+
 	// Routes
 	api := router.Group("/api")
 	{
@@ -208,26 +392,100 @@ func main() {
 
 		// Protected routes
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(db))
 		{
 			// Profile routes
 			profile := protected.Group("/profile")
 			{
 				profile.GET("", profileHandler.GetProfile)
 				profile.PUT("", profileHandler.UpdateProfile)
+				profile.PUT("/pins", postHandler.ReorderPins)
+				profile.PUT("/preferences", profileHandler.UpdatePreferences)
 			}
 
 			// Posts routes
+			// Retries of these requests with the same Idempotency-Key get the first response
+			idempotent := middleware.Idempotency(db, config.IdempotencyWindow())
+
 			posts := protected.Group("/posts")
 			{
-				posts.POST("", postHandler.CreatePost)
+				posts.POST("", idempotent, postHandler.CreatePost)
 				posts.GET("", postHandler.GetPosts)
 				posts.GET("/user", postHandler.GetUserPosts)
+				posts.GET("/trash", postHandler.GetTrash)
+				posts.GET("/drafts", postHandler.GetDrafts)
+				posts.PUT("/:id", postHandler.UpdatePost)
+				posts.DELETE("/:id", postHandler.DeletePost)
+				posts.POST("/:id/restore", postHandler.RestorePost)
+				posts.POST("/:id/publish", postHandler.PublishPost)
+				posts.PUT("/:id/schedule", postHandler.SchedulePost)
+				posts.POST("/:id/repost", idempotent, postHandler.Repost)
+				posts.DELETE("/:id/repost", postHandler.Unrepost)
+				posts.PUT("/:id/reactions/:type", reactionHandler.AddReaction)
+				posts.DELETE("/:id/reactions/:type", reactionHandler.RemoveReaction)
+				posts.POST("/:id/poll/votes", postHandler.VotePoll)
+				posts.PUT("/:id/bookmark", postHandler.Bookmark)
+				posts.DELETE("/:id/bookmark", postHandler.Unbookmark)
+				posts.PUT("/:id/pin", postHandler.PinPost)
+				posts.DELETE("/:id/pin", postHandler.UnpinPost)
+				posts.PUT("/:id/content-warning", postHandler.SetContentWarning)
+				posts.DELETE("/:id/content-warning", postHandler.ClearContentWarning)
+				posts.GET("/:id/revisions", postHandler.GetPostRevisions)
+			}
+
+			// Bookmark routes
+			bookmarks := protected.Group("/bookmarks")
+			{
+				bookmarks.GET("", postHandler.GetBookmarks)
+				bookmarks.GET("/folders", postHandler.GetBookmarkFolders)
+				bookmarks.POST("/folders", postHandler.CreateBookmarkFolder)
+				bookmarks.PUT("/folders/:id", postHandler.RenameBookmarkFolder)
+				bookmarks.DELETE("/folders/:id", postHandler.DeleteBookmarkFolder)
+			}
+
+			// Follow routes
+			protected.PUT("/users/:username/follow", followHandler.Follow)
+			protected.DELETE("/users/:username/follow", followHandler.Unfollow)
+
+			// Media routes
+			protected.POST("/media", mediaHandler.Upload)
+
+			// Import routes
+			imports := protected.Group("/imports")
+			{
+				imports.POST("", importHandler.CreateImport)
+				imports.GET("", importHandler.GetImports)
+				imports.GET("/:id", importHandler.GetImport)
+				imports.DELETE("/:id", importHandler.RollbackImport)
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireRole(models.RoleAdmin))
+			{
+				admin.POST("/users/:id/suspend", adminHandler.SuspendUser)
+				admin.DELETE("/users/:id/suspend", adminHandler.UnsuspendUser)
 			}
 		}
 
 		// Public routes
-		api.GET("/feed", postHandler.GetPublicFeed)
+		api.GET("/reactions/types", reactionHandler.GetReactionTypes)
+		api.GET("/tags", postHandler.SuggestTags)
+
+		// Routes that work anonymously but recognise signed-in users
+		optional := api.Group("/")
+		optional.Use(middleware.OptionalAuthMiddleware(db))
+		{
+			optional.GET("/feed", postHandler.GetPublicFeed)
+			optional.GET("/posts/:id", postHandler.GetPost)
+			optional.GET("/posts/:id/thread", postHandler.GetThread)
+			optional.GET("/tags/:tag/posts", postHandler.GetTagPosts)
+			optional.GET("/search/posts", postHandler.SearchPosts)
+			optional.GET("/users/:username", postHandler.GetUserProfile)
+			optional.GET("/profiles/:id", postHandler.GetUserProfileByID)
+			optional.GET("/users/:username/followers", followHandler.GetFollowers)
+			optional.GET("/users/:username/following", followHandler.GetFollowing)
+		}
 	}
 
 	// Start server
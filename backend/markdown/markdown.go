@@ -0,0 +1,330 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"unleashed-space/config"
+	"unleashed-space/content"
+)
+
+var (
+	bulletItem  = regexp.MustCompile(`^ {0,3}[-*+] +(.*)$`)
+	orderedItem = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)] +(.*)$`)
+)
+
+// Render turns the Markdown source of a post into HTML. Only a small dialect
+// is understood: paragraphs and line breaks, *emphasis*, **strong**, `code`,
+// fenced code blocks, "-" and "1." lists, and [links](https://...). Bare URLs,
+// @mentions of the given users and #hashtags are linked as well. mentioned maps
+// each username as written in src to the ID of the user, which the link points
+// to so that it keeps working after the user is renamed.
+//
+// Raw HTML is never passed through: all text is escaped and links only accept
+// http(s) URLs, so the output is safe to insert into a page as is.
+func Render(src string, mentioned map[string]string) string {
+	r := renderer{base: config.PublicURL(), mentions: mentioned}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var blocks []string
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, "<p>"+strings.Join(paragraph, "<br>\n")+"</p>")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, "<pre><code>"+html.EscapeString(strings.Join(code, "\n"))+"</code></pre>")
+			i++
+
+		case strings.TrimSpace(line) == "":
+			flush()
+			i++
+
+		case bulletItem.MatchString(line):
+			flush()
+			var items []string
+			for ; i < len(lines) && bulletItem.MatchString(lines[i]); i++ {
+				items = append(items, "<li>"+r.inline(bulletItem.FindStringSubmatch(lines[i])[1], true)+"</li>")
+			}
+			blocks = append(blocks, "<ul>\n"+strings.Join(items, "\n")+"\n</ul>")
+
+		case orderedItem.MatchString(line):
+			flush()
+			open := "<ol>"
+			if start := orderedItem.FindStringSubmatch(line)[1]; strings.TrimLeft(start, "0") != "1" {
+				n, _ := strconv.Atoi(start)
+				open = `<ol start="` + strconv.Itoa(n) + `">`
+			}
+			var items []string
+			for ; i < len(lines) && orderedItem.MatchString(lines[i]); i++ {
+				items = append(items, "<li>"+r.inline(orderedItem.FindStringSubmatch(lines[i])[2], true)+"</li>")
+			}
+			blocks = append(blocks, open+"\n"+strings.Join(items, "\n")+"\n</ol>")
+
+		default:
+			paragraph = append(paragraph, r.inline(strings.TrimSpace(line), true))
+			i++
+		}
+	}
+	flush()
+
+	return strings.Join(blocks, "\n")
+}
+
+type renderer struct {
+	base     string
+	mentions map[string]string // username as written -> user ID
+}
+
+// inline renders the spans of one line. links is false inside link text,
+// where nothing else may become a link.
+func (r *renderer) inline(s string, links bool) string {
+	var b strings.Builder
+	l := &line{s: s}
+	for i := 0; i < len(s); {
+		if n := r.span(&b, l, i, links); n > 0 {
+			i += n
+			continue
+		}
+
+		ch, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(string(ch)))
+		i += size
+	}
+	return b.String()
+}
+
+// span renders the construct starting at s[i], if any, and returns how many
+// bytes of s it consumed. It returns 0 when s[i] is plain text.
+func (r *renderer) span(b *strings.Builder, l *line, i int, links bool) int {
+	s := l.s
+	rest := s[i:]
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+
+	switch rest[0] {
+	case '\\':
+		if len(rest) > 1 && strings.IndexByte("\\`*_[]()#@!-+.>~|", rest[1]) >= 0 {
+			b.WriteString(html.EscapeString(rest[1:2]))
+			return 2
+		}
+
+	case '`':
+		ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+		fence := rest[:ticks]
+		if end := l.next(fence, i+ticks, nil); end >= 0 {
+			code := s[i+ticks : end]
+			b.WriteString("<code>" + html.EscapeString(strings.TrimSpace(code)) + "</code>")
+			return end + ticks - i
+		}
+		b.WriteString(fence)
+		return ticks
+
+	case '*', '_':
+		delim := rest[:1]
+		if strings.HasPrefix(rest, delim+delim) {
+			delim += delim
+		}
+		// Underscores inside words, as in snake_case, are literal
+		if delim[0] == '_' && isWordRune(prev) {
+			break
+		}
+		if end := l.closingDelim(i, delim); end > 0 {
+			tag := "em"
+			if len(delim) == 2 {
+				tag = "strong"
+			}
+			b.WriteString("<" + tag + ">" + r.inline(s[i+len(delim):end], links) + "</" + tag + ">")
+			return end + len(delim) - i
+		}
+		b.WriteString(delim)
+		return len(delim)
+
+	case '[':
+		if !links {
+			break
+		}
+		text, target, n := l.linkParts(i)
+		if n > 0 && isWebURL(target) {
+			b.WriteString(anchor(target, "", r.inline(text, false)))
+			return n
+		}
+
+	case 'h':
+		if !links || isWordRune(prev) {
+			break
+		}
+		if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+			break
+		}
+		// Only look as far as the URL can reach
+		token := rest
+		if end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune(`<>"`, r) }); end >= 0 {
+			token = rest[:end]
+		}
+		if locs := content.FindURLs(token); len(locs) > 0 && locs[0][0] == 0 {
+			target := rest[:locs[0][1]]
+			b.WriteString(anchor(target, "", html.EscapeString(target)))
+			return len(target)
+		}
+
+	case '@':
+		if !links || isWordRune(prev) || prev == '@' {
+			break
+		}
+		// A username is at most 30 characters, plus one to see where it ends
+		token := rest
+		if len(token) > 32 {
+			token = token[:32]
+		}
+		if tokens := content.ExtractMentions(token); len(tokens) > 0 && tokens[0].Start == 0 && r.mentions[tokens[0].Username] != "" {
+			username := tokens[0].Username
+			b.WriteString(anchor(r.base+"/profiles/"+url.PathEscape(r.mentions[username]), "mention", "@"+html.EscapeString(username)))
+			return 1 + len(username)
+		}
+
+	case '#':
+		if !links || isWordRune(prev) || strings.ContainsRune("&#/", prev) {
+			break
+		}
+		word := rest[1:]
+		if end := strings.IndexFunc(word, func(r rune) bool { return !isWordRune(r) }); end >= 0 {
+			word = word[:end]
+		}
+		if tag, ok := content.NormalizeTag(word); ok {
+			b.WriteString(anchor(r.base+"/tags/"+url.PathEscape(tag), "hashtag", "#"+html.EscapeString(word)))
+			return 1 + len(word)
+		}
+	}
+	return 0
+}
+
+// line is a string being rendered by inline. It remembers the searches made
+// for closing delimiters, so that unmatched ones do not make every later
+// span scan the rest of the line again, which would take quadratic time.
+type line struct {
+	s        string
+	found    map[string]int // last position found for a delimiter, -1 if none is left
+	brackets []int          // position of the ']' closing each '[', -1 if none
+	parens   []int          // position of the first ')' at or after each position, -1 if none
+}
+
+// next returns the first position at or after lo where delim occurs and ok
+// accepts it, or -1. Whether a position qualifies must not depend on lo, and
+// lo must not decrease between calls for the same delimiter; each part of
+// the line is then scanned at most once per delimiter.
+func (l *line) next(delim string, lo int, ok func(j int) bool) int {
+	if l.found == nil {
+		l.found = make(map[string]int)
+	}
+	if j, seen := l.found[delim]; seen && (j < 0 || j >= lo) {
+		return j
+	}
+	for j := lo; j+len(delim) <= len(l.s); j++ {
+		if l.s[j:j+len(delim)] == delim && (ok == nil || ok(j)) {
+			l.found[delim] = j
+			return j
+		}
+	}
+	l.found[delim] = -1
+	return -1
+}
+
+// closingDelim finds the delimiter closing the emphasis that opens at i.
+// Emphasis must hug its text: "* a *" is not emphasis.
+func (l *line) closingDelim(i int, delim string) int {
+	s, n := l.s, len(delim)
+	if len(s)-i <= n || s[i+n] == ' ' {
+		return -1
+	}
+	return l.next(delim, i+n+1, func(j int) bool {
+		if s[j-1] == ' ' {
+			return false
+		}
+		// A single * must not be part of a longer run
+		if n == 1 && (s[j-1] == delim[0] || (j+1 < len(s) && s[j+1] == delim[0])) {
+			return false
+		}
+		after, _ := utf8.DecodeRuneInString(s[j+n:])
+		return delim[0] != '_' || !isWordRune(after)
+	})
+}
+
+// linkParts splits "[text](target)" at s[i] and returns its length, or 0
+func (l *line) linkParts(i int) (text, target string, n int) {
+	s := l.s
+	if l.brackets == nil {
+		l.brackets = make([]int, len(s))
+		l.parens = make([]int, len(s)+1)
+		var open []int
+		for j := 0; j < len(s); j++ {
+			l.brackets[j] = -1
+			switch s[j] {
+			case '[':
+				open = append(open, j)
+			case ']':
+				if len(open) > 0 {
+					l.brackets[open[len(open)-1]] = j
+					open = open[:len(open)-1]
+				}
+			}
+		}
+		l.parens[len(s)] = -1
+		for j := len(s) - 1; j >= 0; j-- {
+			l.parens[j] = l.parens[j+1]
+			if s[j] == ')' {
+				l.parens[j] = j
+			}
+		}
+	}
+
+	j := l.brackets[i]
+	if j < 0 || j+1 >= len(s) || s[j+1] != '(' {
+		return "", "", 0
+	}
+	end := l.parens[j+2]
+	if end < 0 {
+		return "", "", 0
+	}
+	target = strings.TrimSpace(s[j+2 : end])
+	if target == "" || strings.ContainsAny(target, " \t") {
+		return "", "", 0
+	}
+	return s[i+1 : j], target, end + 1 - i
+}
+
+func isWebURL(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// anchor builds a link. External links get rel attributes that stop them
+// from passing ranking or the opener to the target.
+func anchor(href, class, inner string) string {
+	attrs := `href="` + html.EscapeString(href) + `"`
+	if class != "" {
+		attrs += ` class="` + class + `"`
+	} else {
+		attrs += ` rel="nofollow noopener noreferrer"`
+	}
+	return "<a " + attrs + ">" + inner + "</a>"
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
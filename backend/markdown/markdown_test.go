@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderInline(t *testing.T) {
+	cases := map[string]string{
+		"*a* **b** _c_ __d__":                    "<p><em>a</em> <strong>b</strong> <em>c</em> <strong>d</strong></p>",
+		"`e` ``f`g``":                            "<p><code>e</code> <code>f`g</code></p>",
+		"*a **b** c*":                            "<p><em>a <strong>b</strong> c</em></p>",
+		"a * b * c":                              "<p>a * b * c</p>",
+		"snake_case_name":                        "<p>snake_case_name</p>",
+		"*a":                                     "<p>*a</p>",
+		"[x](https://a.b/c)":                     `<p><a href="https://a.b/c" rel="nofollow noopener noreferrer">x</a></p>`,
+		"[y](javascript:alert(1))":               "<p>[y](javascript:alert(1))</p>",
+		"[[a](https://x.y)](https://z.w)":        `<p><a href="https://z.w" rel="nofollow noopener noreferrer">[a](https://x.y)</a></p>`,
+		"see https://a.b/c). and @bob <b>hi</b>": `<p>see <a href="https://a.b/c" rel="nofollow noopener noreferrer">https://a.b/c</a>). and <a href="http://localhost:3000/profiles/64b7f0c2a1b2c3d4e5f60718" class="mention">@bob</a> &lt;b&gt;hi&lt;/b&gt;</p>`,
+	}
+	t.Setenv("PUBLIC_URL", "http://localhost:3000")
+	for src, want := range cases {
+		if got := Render(src, map[string]string{"bob": "64b7f0c2a1b2c3d4e5f60718"}); got != want {
+			t.Errorf("Render(%q)\n got %q\nwant %q", src, got, want)
+		}
+	}
+}
+
+// Unmatched delimiters used to make every later one scan to the end of the
+// line, so these took seconds to render
+func TestRenderUnmatchedDelimitersIsLinear(t *testing.T) {
+	inputs := map[string]string{
+		"emphasis":   strings.Repeat("*a ", 40000),
+		"underscore": strings.Repeat("_a ", 40000),
+		"strong":     strings.Repeat("**a ", 30000),
+		"code":       strings.Repeat("`a ", 40000),
+		"link":       strings.Repeat("[a](", 30000),
+		"bracket":    strings.Repeat("[a ", 40000),
+		"mention":    strings.Repeat("@bob ", 30000),
+		"url":        strings.Repeat("h ", 60000),
+		"brackets":   "https://x.y/" + strings.Repeat(")", 120000),
+	}
+	for name, src := range inputs {
+		start := time.Now()
+		Render(src, map[string]string{"bob": "64b7f0c2a1b2c3d4e5f60718"})
+		// Linear rendering takes milliseconds; the budget leaves room for slow machines
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: rendering %d bytes took %v", name, len(src), elapsed)
+		}
+	}
+}
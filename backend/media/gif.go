@@ -0,0 +1,86 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// maxGIFFramePixels caps the pixels of all frames of a GIF together. Each
+// frame is decoded into its own image, so a small file with thousands of
+// full-size frames would otherwise take gigabytes of memory.
+const maxGIFFramePixels = maxPixels
+
+var errInvalidGIF = errors.New("invalid gif")
+
+// gifFramePixels adds up the size of every frame of a GIF by walking its
+// blocks, without decoding any image data. It stops with ErrTooLarge as soon
+// as the total passes maxGIFFramePixels.
+func gifFramePixels(data []byte) (int, error) {
+	if len(data) < 13 {
+		return 0, errInvalidGIF
+	}
+
+	// Header and logical screen descriptor, then the global color table
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+
+	total := 0
+	for {
+		if i >= len(data) {
+			return 0, errInvalidGIF
+		}
+		switch data[i] {
+		case 0x21: // extension: introducer, label and data sub-blocks
+			next, err := skipSubBlocks(data, i+2)
+			if err != nil {
+				return 0, err
+			}
+			i = next
+
+		case 0x2C: // image descriptor, local color table, LZW code size and data sub-blocks
+			if i+10 > len(data) {
+				return 0, errInvalidGIF
+			}
+			width := int(binary.LittleEndian.Uint16(data[i+5:]))
+			height := int(binary.LittleEndian.Uint16(data[i+7:]))
+			total += width * height
+			if total > maxGIFFramePixels {
+				return 0, ErrTooLarge
+			}
+
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			next, err := skipSubBlocks(data, i+1)
+			if err != nil {
+				return 0, err
+			}
+			i = next
+
+		case 0x3B: // trailer
+			return total, nil
+
+		default:
+			return 0, errInvalidGIF
+		}
+	}
+}
+
+// skipSubBlocks returns the position after the data sub-blocks starting at i
+func skipSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errInvalidGIF
+		}
+		size := int(data[i])
+		i++
+		if size == 0 {
+			return i, nil
+		}
+		i += size
+	}
+}
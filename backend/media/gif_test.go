@@ -0,0 +1,91 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+func encodeGIF(t *testing.T, frames int, w, h int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, w, h), palette.Plan9))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// bombGIF builds a GIF with a w x h screen and the given number of
+// full-screen frames, each with an empty image data block
+func bombGIF(frames, w, h int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, uint16(w))
+	data = binary.LittleEndian.AppendUint16(data, uint16(h))
+	data = append(data, 0, 0, 0)
+	for i := 0; i < frames; i++ {
+		data = append(data, 0x21, 0xF9, 4, 0, 10, 0, 0, 0) // graphic control extension
+		data = append(data, 0x2C, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(w))
+		data = binary.LittleEndian.AppendUint16(data, uint16(h))
+		data = append(data, 0, 2, 0)
+	}
+	return append(data, 0x3B)
+}
+
+func TestGIFFramePixels(t *testing.T) {
+	got, err := gifFramePixels(encodeGIF(t, 3, 20, 10))
+	if err != nil || got != 600 {
+		t.Errorf("got %d, %v, want 600", got, err)
+	}
+}
+
+func TestGIFFramePixelsRejectsTooManyFrames(t *testing.T) {
+	// 2000 frames of 200x200 is only a few kilobytes but 80M pixels
+	data := bombGIF(2000, 200, 200)
+	if _, err := gifFramePixels(data); err != ErrTooLarge {
+		t.Errorf("gifFramePixels = %v, want ErrTooLarge", err)
+	}
+	if _, err := Process(data); err != ErrTooLarge {
+		t.Errorf("Process = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestGIFFramePixelsInvalid(t *testing.T) {
+	valid := encodeGIF(t, 2, 4, 4)
+	for n := 0; n < len(valid); n++ {
+		if _, err := gifFramePixels(valid[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: no error", n)
+		}
+	}
+
+	bad := append([]byte{}, valid...)
+	bad[len(bad)-1] = 0x00
+	if _, err := gifFramePixels(bad); err == nil {
+		t.Error("unknown block: no error")
+	}
+}
+
+func TestProcessAnimatedGIF(t *testing.T) {
+	img, err := Process(encodeGIF(t, 3, 20, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/gif" || img.Width != 20 || img.Height != 10 {
+		t.Errorf("got %s %dx%d", img.ContentType, img.Width, img.Height)
+	}
+	anim, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 {
+		t.Errorf("re-encoded GIF has %d frames, want 3", len(anim.Image))
+	}
+}
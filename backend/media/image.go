@@ -0,0 +1,224 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ThumbnailSize is the longest side of generated thumbnails, in pixels
+const ThumbnailSize = 320
+
+// maxPixels guards against decompression bombs: tiny files that decode to huge images
+const maxPixels = 40_000_000
+
+// ErrUnsupportedType is returned for uploads that are not JPEG, PNG or GIF images
+var ErrUnsupportedType = errors.New("unsupported media type, only JPEG, PNG and GIF images are accepted")
+
+// ErrTooLarge is returned for images whose dimensions exceed maxPixels
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+// Image is an upload that has been validated and cleaned
+type Image struct {
+	Data        []byte // re-encoded image with all metadata removed
+	Thumbnail   []byte
+	ContentType string
+	ThumbType   string
+	Ext         string
+	ThumbExt    string
+	Width       int
+	Height      int
+}
+
+// Process sniffs the real type of an upload (ignoring what the client claimed),
+// re-encodes it to drop EXIF and other metadata after applying the EXIF
+// orientation, and generates a thumbnail.
+func Process(data []byte) (*Image, error) {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return processJPEG(data)
+	case "image/png":
+		return processPNG(data)
+	case "image/gif":
+		return processGIF(data)
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
+
+func processJPEG(data []byte) (*Image, error) {
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	oriented := applyOrientation(toRGBA(img), jpegOrientation(data))
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, oriented, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	thumb, err := encodeJPEG(thumbnail(oriented))
+	if err != nil {
+		return nil, err
+	}
+
+	b := oriented.Bounds()
+	return &Image{
+		Data:        out.Bytes(),
+		Thumbnail:   thumb,
+		ContentType: "image/jpeg",
+		ThumbType:   "image/jpeg",
+		Ext:         "jpg",
+		ThumbExt:    "jpg",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+func processPNG(data []byte) (*Image, error) {
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-encoding keeps only pixel data, dropping text and eXIf chunks
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(toRGBA(img))); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	return &Image{
+		Data:        out.Bytes(),
+		Thumbnail:   thumb.Bytes(),
+		ContentType: "image/png",
+		ThumbType:   "image/png",
+		Ext:         "png",
+		ThumbExt:    "png",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+func processGIF(data []byte) (*Image, error) {
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+	if _, err := gifFramePixels(data); err != nil {
+		return nil, err
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(anim.Image) == 0 {
+		return nil, errors.New("gif has no frames")
+	}
+
+	// Re-encoding keeps frames and timing but drops comment and application extensions
+	var out bytes.Buffer
+	if err := gif.EncodeAll(&out, anim); err != nil {
+		return nil, err
+	}
+
+	// The thumbnail is a still of the first frame
+	var thumb bytes.Buffer
+	if err := png.Encode(&thumb, thumbnail(toRGBA(anim.Image[0]))); err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:        out.Bytes(),
+		Thumbnail:   thumb.Bytes(),
+		ContentType: "image/gif",
+		ThumbType:   "image/png",
+		Ext:         "gif",
+		ThumbExt:    "png",
+		Width:       anim.Config.Width,
+		Height:      anim.Config.Height,
+	}, nil
+}
+
+func checkDimensions(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+func encodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// thumbnail scales img down to fit in ThumbnailSize x ThumbnailSize by averaging
+// the source pixels that fall into each target pixel. Small images are returned as is.
+func thumbnail(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= ThumbnailSize && h <= ThumbnailSize {
+		return img
+	}
+
+	tw, th := ThumbnailSize, ThumbnailSize
+	if w > h {
+		th = max(1, h*ThumbnailSize/w)
+	} else {
+		tw = max(1, w*ThumbnailSize/h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, max((x+1)*w/tw, x*w/tw+1)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := img.RGBAAt(sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					bl += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
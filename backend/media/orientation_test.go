@@ -0,0 +1,140 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// exifJPEG builds the start of a JPEG whose APP1 segment holds an EXIF block
+// with a single IFD0 entry for the orientation tag
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112) // tag
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)      // count
+	order.PutUint16(tiff[18:], orientation)
+
+	return jpegWithSegment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func jpegWithSegment(marker byte, payload []byte) []byte {
+	data := []byte{0xFF, 0xD8, 0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(payload)+2))
+	data = append(data, payload...)
+	return append(data, 0xFF, 0xDA, 0, 2)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		for want := 1; want <= 8; want++ {
+			if got := jpegOrientation(exifJPEG(order, uint16(want))); got != want {
+				t.Errorf("%v orientation %d: got %d", order, want, got)
+			}
+		}
+	}
+}
+
+func TestJPEGOrientationSkipsOtherSegments(t *testing.T) {
+	// A JFIF APP0 segment before the EXIF one
+	app0 := jpegWithSegment(0xE0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	data := append(app0[:len(app0)-4], exifJPEG(binary.BigEndian, 6)[2:]...)
+
+	if got := jpegOrientation(data); got != 6 {
+		t.Errorf("got %d, want 6", got)
+	}
+}
+
+func TestJPEGOrientationInvalid(t *testing.T) {
+	valid := exifJPEG(binary.LittleEndian, 6)
+
+	cases := map[string][]byte{
+		"empty":              nil,
+		"not a JPEG":         []byte("\x89PNG\r\n\x1a\n"),
+		"no EXIF":            jpegWithSegment(0xE0, []byte("JFIF\x00")),
+		"bad byte order":     append(append([]byte{}, valid[:12]...), append([]byte("XX"), valid[14:]...)...),
+		"out of range value": exifJPEG(binary.LittleEndian, 9),
+		"zero value":         exifJPEG(binary.BigEndian, 0),
+		"segment too short":  {0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01},
+		"segment too long":   {0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x', 'i', 'f'},
+		"no marker":          {0xFF, 0xD8, 0x00, 0xE1, 0x00, 0x04, 0x00, 0x00},
+	}
+
+	// IFD0 offset pointing past the end of the block
+	badOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(badOffset[6+6+4:], 0xFFFFFF00)
+	cases["IFD offset out of range"] = badOffset
+
+	// More entries than the block holds, none of them the orientation
+	badCount := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(badCount[6+6+8:], 0xFFFF)
+	binary.LittleEndian.PutUint16(badCount[6+6+10:], 0x010F)
+	cases["entry count out of range"] = badCount
+
+	for name, data := range cases {
+		if got := jpegOrientation(data); got != 1 {
+			t.Errorf("%s: got %d, want 1", name, got)
+		}
+	}
+}
+
+// Every prefix of a valid file must be handled without panicking
+func TestJPEGOrientationTruncated(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := exifJPEG(order, 8)
+		for n := 0; n < len(data); n++ {
+			if got := jpegOrientation(data[:n]); got != 1 && got != 8 {
+				t.Errorf("%v truncated to %d bytes: got %d", order, n, got)
+			}
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 2x1 image: red on the left, blue on the right
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+
+	cases := []struct {
+		orientation int
+		w, h        int
+		pixels      []color.RGBA // row by row
+	}{
+		{1, 2, 1, []color.RGBA{red, blue}},
+		{2, 2, 1, []color.RGBA{blue, red}},
+		{3, 2, 1, []color.RGBA{blue, red}},
+		{4, 2, 1, []color.RGBA{red, blue}},
+		{5, 1, 2, []color.RGBA{red, blue}},
+		{6, 1, 2, []color.RGBA{red, blue}},
+		{7, 1, 2, []color.RGBA{blue, red}},
+		{8, 1, 2, []color.RGBA{blue, red}},
+	}
+	for _, tc := range cases {
+		dst := applyOrientation(src, tc.orientation)
+		if b := dst.Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tc.orientation, b.Dx(), b.Dy(), tc.w, tc.h)
+			continue
+		}
+		i := 0
+		for y := 0; y < tc.h; y++ {
+			for x := 0; x < tc.w; x++ {
+				if got := dst.RGBAAt(x, y); got != tc.pixels[i] {
+					t.Errorf("orientation %d: pixel (%d,%d) = %v, want %v", tc.orientation, x, y, got, tc.pixels[i])
+				}
+				i++
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -8,10 +9,14 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -63,8 +68,23 @@ func AuthMiddleware() gin.HandlerFunc {
 				return
 			}
 
-			// Set user ID in context
+			// Load the account so suspensions take effect immediately
+			var user models.User
+			err = db.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+				c.Abort()
+				return
+			}
+			if user.IsSuspended() {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account suspended", "suspension": user.Suspension})
+				c.Abort()
+				return
+			}
+
+			// Set user ID and role in context
 			c.Set("user_id", userID)
+			c.Set("user_role", user.Role)
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -73,3 +93,33 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// OptionalAuthMiddleware authenticates the caller when an Authorization header is
+// present and lets anonymous requests through without a user_id otherwise.
+func OptionalAuthMiddleware(db *mongo.Database) gin.HandlerFunc {
+	auth := AuthMiddleware(db)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
+
+// RequireRole only lets through users whose role is one of the given roles.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
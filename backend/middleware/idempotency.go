@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"unleashed-space/models"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodyBytes caps the request body read to hash it. It only
+	// needs to fit the JSON bodies of the routes this guards.
+	maxIdempotentBodyBytes = 1 << 20
+
+	// idempotencyLock is how long a request in progress holds its key. If the
+	// server dies mid-request, a retry may run again after this.
+	idempotencyLock = time.Minute
+)
+
+// Idempotency makes requests that carry an Idempotency-Key header safe to
+// retry. The first response with a status below 500 is stored for window and
+// replayed for later requests from the same caller to the same route with
+// the same key. Reusing a key with a different body is rejected, as is a
+// retry while the first request is still in progress. Requests without the
+// header are passed through.
+//
+// It must run after AuthMiddleware, as keys are scoped to the caller.
+func Idempotency(db *mongo.Database, window time.Duration) gin.HandlerFunc {
+	records := db.Collection("idempotency_keys")
+
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := c.Get("user_id")
+		ctx := context.Background()
+		now := time.Now()
+		record := models.IdempotencyRecord{
+			ID:          recordID(userID, c.Request.Method, c.Request.URL.Path, key),
+			RequestHash: hash(body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyLock),
+		}
+
+		// Claim the key. A record left over past its expiry is fair game,
+		// even if the TTL monitor has not removed it yet.
+		_, err = records.InsertOne(ctx, record)
+		if mongo.IsDuplicateKeyError(err) {
+			_, err = records.DeleteOne(ctx, bson.M{"_id": record.ID, "expires_at": bson.M{"$lte": now}})
+			if err == nil {
+				_, err = records.InsertOne(ctx, record)
+			}
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			replay(c, records, record)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Only touch the record this request claimed, in case its lock ran
+		// out and a retry claimed the key since
+		claimed := bson.M{"_id": record.ID, "created_at": record.CreatedAt}
+
+		// Server errors are not remembered, so the request can be retried
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if _, err := records.DeleteOne(ctx, claimed); err != nil {
+				log.Printf("Error releasing Idempotency-Key: %v", err)
+			}
+			return
+		}
+
+		_, err = records.UpdateOne(ctx, claimed, bson.M{"$set": bson.M{
+			"completed":    true,
+			"status":       status,
+			"content_type": recorder.Header().Get("Content-Type"),
+			"body":         recorder.body.Bytes(),
+			"expires_at":   time.Now().Add(window),
+		}})
+		if err != nil {
+			log.Printf("Error storing response for Idempotency-Key: %v", err)
+		}
+	}
+}
+
+// replay answers a request whose key is already taken, with the stored
+// response if the request matches the one that took it
+func replay(c *gin.Context, records *mongo.Collection, request models.IdempotencyRecord) {
+	defer c.Abort()
+
+	var stored models.IdempotencyRecord
+	if err := records.FindOne(context.Background(), bson.M{"_id": request.ID}).Decode(&stored); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// The first request just failed and released the key
+			c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key was just processed, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+		return
+	}
+	answer(c, stored, request)
+}
+
+// answer responds to a retry of stored: with the stored response if the
+// retry matches it and it has completed, or with an error otherwise
+func answer(c *gin.Context, stored, request models.IdempotencyRecord) {
+	switch {
+	case stored.RequestHash != request.RequestHash:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+	case !stored.Completed:
+		c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// recordID scopes key to the caller and the route it was sent to
+func recordID(userID any, method, path, key string) string {
+	return hash([]byte(fmt.Sprint(userID) + "\n" + method + " " + path + "\n" + key))
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
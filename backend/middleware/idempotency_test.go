@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"unleashed-space/models"
+)
+
+func TestRecordID(t *testing.T) {
+	user, other := primitive.NewObjectID(), primitive.NewObjectID()
+	id := recordID(user, http.MethodPost, "/api/posts", "abc")
+
+	if id != recordID(user, http.MethodPost, "/api/posts", "abc") {
+		t.Error("same request: different IDs")
+	}
+	if len(id) != 64 {
+		t.Errorf("ID %q is not a hex SHA-256", id)
+	}
+
+	for name, got := range map[string]string{
+		"other user":   recordID(other, http.MethodPost, "/api/posts", "abc"),
+		"anonymous":    recordID(nil, http.MethodPost, "/api/posts", "abc"),
+		"other method": recordID(user, http.MethodPut, "/api/posts", "abc"),
+		"other path":   recordID(user, http.MethodPost, "/api/posts/1", "abc"),
+		"other key":    recordID(user, http.MethodPost, "/api/posts", "abd"),
+		// The separators keep parts from running into each other
+		"shifted": recordID(user, http.MethodPost, "/api/posts\nabc", ""),
+	} {
+		if got == id {
+			t.Errorf("%s: same ID", name)
+		}
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	request := models.IdempotencyRecord{ID: "k", RequestHash: hash([]byte(`{"content":"hi"}`))}
+
+	cases := map[string]struct {
+		stored   models.IdempotencyRecord
+		status   int
+		replayed bool
+	}{
+		"replay": {
+			models.IdempotencyRecord{RequestHash: request.RequestHash, Completed: true, Status: http.StatusCreated, ContentType: "application/json", Body: []byte(`{"id":"1"}`)},
+			http.StatusCreated, true,
+		},
+		"replayed client error": {
+			models.IdempotencyRecord{RequestHash: request.RequestHash, Completed: true, Status: http.StatusBadRequest, ContentType: "application/json", Body: []byte(`{"error":"x"}`)},
+			http.StatusBadRequest, true,
+		},
+		"different body": {
+			models.IdempotencyRecord{RequestHash: hash([]byte(`{"content":"bye"}`)), Completed: true, Status: http.StatusCreated},
+			http.StatusUnprocessableEntity, false,
+		},
+		"different body in progress": {
+			models.IdempotencyRecord{RequestHash: hash([]byte(`{}`))},
+			http.StatusUnprocessableEntity, false,
+		},
+		"in progress": {
+			models.IdempotencyRecord{RequestHash: request.RequestHash},
+			http.StatusConflict, false,
+		},
+	}
+	for name, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		answer(c, tc.stored, request)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", name, w.Code, tc.status)
+		}
+		if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tc.replayed {
+			t.Errorf("%s: replayed %v, want %v", name, replayed, tc.replayed)
+		}
+		if tc.replayed && (w.Body.String() != string(tc.stored.Body) || w.Header().Get("Content-Type") != tc.stored.ContentType) {
+			t.Errorf("%s: body %q (%s), want the stored response", name, w.Body.String(), w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// The checks below run before the database is used, so the client never
+// needs to reach a server
+func testIdempotencyRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1").SetServerSelectionTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	r := gin.New()
+	r.POST("/posts", Idempotency(client.Database("test"), time.Hour), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	return r
+}
+
+func TestIdempotencyRejects(t *testing.T) {
+	r := testIdempotencyRouter(t)
+
+	cases := map[string]struct {
+		key    string
+		body   string
+		status int
+	}{
+		"no key":       {"", strings.Repeat("a", maxIdempotentBodyBytes+1), http.StatusCreated},
+		"long key":     {strings.Repeat("k", maxIdempotencyKeyLength+1), "{}", http.StatusBadRequest},
+		"body too big": {"abc", strings.Repeat("a", maxIdempotentBodyBytes+1), http.StatusRequestEntityTooLarge},
+	}
+	for name, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(tc.body))
+		if tc.key != "" {
+			req.Header.Set("Idempotency-Key", tc.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", name, w.Code, tc.status)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bookmark is a post a user saved for later, optionally filed in a folder.
+// Bookmarks are private to the user who made them.
+type Bookmark struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	PostID    primitive.ObjectID  `bson:"post_id" json:"post_id"`
+	FolderID  *primitive.ObjectID `bson:"folder_id,omitempty" json:"folder_id,omitempty"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+
+	// The bookmarked post, filled in per request
+	Post *Post `bson:"-" json:"post,omitempty"`
+}
+
+// BookmarkFolder is a named group of a user's bookmarks
+type BookmarkFolder struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type BookmarkInput struct {
+	FolderID string `json:"folder_id"`
+}
+
+type BookmarkFolderInput struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
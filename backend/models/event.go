@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Event types
+const (
+	EventMention    = "mention"
+	EventPollClosed = "poll_closed"
+)
+
+// Event is something that happened to a user, queued for consumers such as notifications.
+// ClaimedAt is set once a consumer has taken the event.
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Type      string             `bson:"type" json:"type"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	ActorID   primitive.ObjectID `bson:"actor_id" json:"actor_id"`
+	PostID    primitive.ObjectID `bson:"post_id,omitempty" json:"post_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ClaimedAt *time.Time         `bson:"claimed_at,omitempty" json:"claimed_at,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Follow records that one user follows another. Following is one-way; two
+// users who follow each other are mutuals.
+type Follow struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	FollowerID primitive.ObjectID `bson:"follower_id" json:"follower_id"`
+	FolloweeID primitive.ObjectID `bson:"followee_id" json:"followee_id"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Relationship describes how the caller and another user follow each other
+type Relationship struct {
+	Following  bool `json:"following"`   // the caller follows the user
+	FollowedBy bool `json:"followed_by"` // the user follows the caller
+	Mutual     bool `json:"mutual"`
+}
+
+// FollowListItem is a user in a followers or following list
+type FollowListItem struct {
+	ID         primitive.ObjectID `json:"id"`
+	Name       string             `json:"name"`
+	Username   string             `json:"username"`
+	FollowedAt time.Time          `json:"followed_at"`
+
+	// Set for signed-in callers
+	Relationship *Relationship `json:"relationship,omitempty"`
+}
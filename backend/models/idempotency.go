@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request made with an
+// Idempotency-Key header, so retries of the request get the same response
+type IdempotencyRecord struct {
+	ID          string    `bson:"_id"`          // hash of the caller, route and key
+	RequestHash string    `bson:"request_hash"` // hash of the request body
+	Completed   bool      `bson:"completed"`
+	Status      int       `bson:"status,omitempty"`
+	ContentType string    `bson:"content_type,omitempty"`
+	Body        []byte    `bson:"body,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at"` // removed by a TTL index
+}
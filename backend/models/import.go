@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportJob tracks the import of an archive exported from another service.
+// The import runs in the background; the counters report its progress.
+type ImportJob struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Source       string             `bson:"source" json:"source"`
+	Status       string             `bson:"status" json:"status"`
+	ArchiveKey   string             `bson:"archive_key,omitempty" json:"-"` // the uploaded archive in media storage
+	Total        int                `bson:"total" json:"total"`             // entries found in the archive
+	Processed    int                `bson:"processed" json:"processed"`
+	Imported     int                `bson:"imported" json:"imported"`
+	Duplicates   int                `bson:"duplicates" json:"duplicates"` // already imported before
+	Skipped      int                `bson:"skipped" json:"skipped"`       // boosts, retweets and empty entries
+	Failed       int                `bson:"failed" json:"failed"`
+	SkippedMedia int                `bson:"skipped_media" json:"skipped_media"` // unsupported or missing files
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	FinishedAt   *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// Import job statuses. A job that is running or rolling back is taken over by
+// another server instance if it stops reporting progress.
+const (
+	ImportPending         = "pending"
+	ImportRunning         = "running"
+	ImportCompleted       = "completed"
+	ImportFailed          = "failed"
+	ImportRollbackPending = "rollback_pending"
+	ImportRollingBack     = "rolling_back"
+	ImportRolledBack      = "rolled_back"
+)
+
+// PostImport records where an imported post came from
+type PostImport struct {
+	JobID    primitive.ObjectID `bson:"job_id"`
+	Source   string             `bson:"source"`
+	SourceID string             `bson:"source_id"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"unleashed-space/config"
+)
+
+// MaxAttachments is the number of media files a post can carry
+const MaxAttachments = 4
+
+// Media is an uploaded file. PostID is set once it is attached to a post.
+type Media struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	PostID       *primitive.ObjectID `bson:"post_id,omitempty" json:"post_id,omitempty"`
+	Key          string              `bson:"key" json:"-"`
+	ThumbnailKey string              `bson:"thumbnail_key" json:"-"`
+	ContentType  string              `bson:"content_type" json:"content_type"`
+	Width        int                 `bson:"width" json:"width"`
+	Height       int                 `bson:"height" json:"height"`
+	Size         int                 `bson:"size" json:"size"`
+	AltText      string              `bson:"alt_text" json:"alt_text"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// MarshalJSON adds the public URLs of the file and its thumbnail
+func (m Media) MarshalJSON() ([]byte, error) {
+	type media Media
+	return json.Marshal(struct {
+		media
+		URL          string `json:"url"`
+		ThumbnailURL string `json:"thumbnail_url"`
+	}{media(m), MediaURL(m.Key), MediaURL(m.ThumbnailKey)})
+}
+
+// Attachment is the copy of a Media kept on the post it is attached to
+type Attachment struct {
+	MediaID      primitive.ObjectID `bson:"media_id" json:"media_id"`
+	Key          string             `bson:"key" json:"-"`
+	ThumbnailKey string             `bson:"thumbnail_key" json:"-"`
+	ContentType  string             `bson:"content_type" json:"content_type"`
+	Width        int                `bson:"width" json:"width"`
+	Height       int                `bson:"height" json:"height"`
+	AltText      string             `bson:"alt_text" json:"alt_text"`
+}
+
+// MarshalJSON adds the public URLs of the file and its thumbnail
+func (a Attachment) MarshalJSON() ([]byte, error) {
+	type attachment Attachment
+	return json.Marshal(struct {
+		attachment
+		URL          string `json:"url"`
+		ThumbnailURL string `json:"thumbnail_url"`
+	}{attachment(a), MediaURL(a.Key), MediaURL(a.ThumbnailKey)})
+}
+
+// MediaURL returns the public URL a stored media key is served from
+func MediaURL(key string) string {
+	return config.APIURL() + "/media/" + key
+}
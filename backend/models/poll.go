@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Poll is a vote attached to a post. Vote counts are kept on the poll and
+// updated atomically as votes come in.
+type Poll struct {
+	Options    []PollOption `bson:"options" json:"options"`
+	Multiple   bool         `bson:"multiple" json:"multiple"`
+	ClosesAt   time.Time    `bson:"closes_at" json:"closes_at"`
+	Closed     bool         `bson:"closed" json:"closed"` // set by the closing job once ClosesAt has passed
+	VoterCount int          `bson:"voter_count" json:"voter_count"`
+
+	// Duration is how long the poll runs once its post is published. ClosesAt
+	// is moved to match when a draft or scheduled post goes live later.
+	Duration time.Duration `bson:"duration,omitempty" json:"-"`
+
+	// Viewer-specific fields, filled in per request for signed-in callers
+	Voted         bool  `bson:"-" json:"voted"`
+	ViewerChoices []int `bson:"-" json:"viewer_choices,omitempty"`
+}
+
+type PollOption struct {
+	Title string `bson:"title" json:"title"`
+	Votes int    `bson:"votes" json:"votes"`
+}
+
+// IsClosed reports whether voting has ended, even if the closing job has not run yet
+func (p Poll) IsClosed() bool {
+	return p.Closed || !time.Now().Before(p.ClosesAt)
+}
+
+// MarshalJSON leaves out the results until the viewer has voted or the poll
+// has closed, so early results cannot sway the vote
+func (p Poll) MarshalJSON() ([]byte, error) {
+	type poll Poll
+	p.Closed = p.IsClosed()
+	if p.Closed || p.Voted {
+		return json.Marshal(poll(p))
+	}
+
+	type hiddenOption struct {
+		Title string `json:"title"`
+	}
+	options := make([]hiddenOption, 0, len(p.Options))
+	for _, o := range p.Options {
+		options = append(options, hiddenOption{Title: o.Title})
+	}
+	return json.Marshal(struct {
+		poll
+		Options       []hiddenOption `json:"options"`
+		VoterCount    *int           `json:"voter_count,omitempty"`
+		ResultsHidden bool           `json:"results_hidden"`
+	}{poll: poll(p), Options: options, ResultsHidden: true})
+}
+
+// PollVote records the choices of one user in one poll. A unique index on
+// (post_id, user_id) enforces a single vote per user.
+type PollVote struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"post_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Choices   []int              `bson:"choices" json:"choices"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PollInput struct {
+	Options  []string  `json:"options" binding:"required,min=2,max=10,dive,required,max=100"`
+	Multiple bool      `json:"multiple"`
+	ClosesAt time.Time `json:"closes_at" binding:"required"`
+}
+
+type VoteInput struct {
+	Choices []int `json:"choices" binding:"required,min=1"`
+}
@@ -1,25 +1,211 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"unleashed-space/config"
+	"unleashed-space/markdown"
 )
 
 type Post struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Content   string             `bson:"content" json:"content"`
-	Author    PostAuthor         `bson:"author" json:"author"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID      primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Content     string               `bson:"content" json:"content"` // Markdown source
+	ContentHTML string               `bson:"content_html,omitempty" json:"content_html"`
+	Author      PostAuthor           `bson:"author" json:"author"`
+	Tags        []string             `bson:"tags,omitempty" json:"tags,omitempty"`
+	Mentions    []Mention            `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	Attachments []Attachment         `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Visibility  string               `bson:"visibility,omitempty" json:"visibility,omitempty"`
+	Warning     string               `bson:"content_warning,omitempty" json:"content_warning,omitempty"`
+	Sensitive   bool                 `bson:"sensitive,omitempty" json:"sensitive,omitempty"`
+	WarningBy   *primitive.ObjectID  `bson:"warning_set_by,omitempty" json:"warning_set_by,omitempty"` // the moderator who applied the warning, if any
+	Status      string               `bson:"status,omitempty" json:"status,omitempty"`                 // empty once published
+	PublishAt   *time.Time           `bson:"publish_at,omitempty" json:"publish_at,omitempty"`
+	ExpiresAt   *time.Time           `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	PreviewURL  string               `bson:"preview_url,omitempty" json:"-"` // first URL in Content, the one previewed
+	LinkPreview *LinkPreview         `bson:"link_preview,omitempty" json:"link_preview,omitempty"`
+	Poll        *Poll                `bson:"poll,omitempty" json:"poll,omitempty"`
+	InReplyTo   *primitive.ObjectID  `bson:"in_reply_to,omitempty" json:"in_reply_to,omitempty"`
+	RootID      *primitive.ObjectID  `bson:"root_id,omitempty" json:"root_id,omitempty"`
+	Ancestors   []primitive.ObjectID `bson:"ancestors,omitempty" json:"-"` // root first, direct parent last
+	Depth       int                  `bson:"depth" json:"depth"`
+	RepostOf    *primitive.ObjectID  `bson:"repost_of,omitempty" json:"repost_of,omitempty"`
+	QuoteOf     *primitive.ObjectID  `bson:"quote_of,omitempty" json:"quote_of,omitempty"`
+	RepostCount int                  `bson:"repost_count" json:"repost_count"`
+	QuoteCount  int                  `bson:"quote_count" json:"quote_count"`
+	ReplyCount  int                  `bson:"reply_count" json:"reply_count"`
+	Reactions   map[string]int       `bson:"reactions,omitempty" json:"reactions"`
+	Edited      bool                 `bson:"edited" json:"edited"`
+	EditedAt    *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	DeletedAt   *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy   *primitive.ObjectID  `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	Import      *PostImport          `bson:"import,omitempty" json:"-"`
+	// AuthorSuspended hides the post from shared listings while its author is suspended
+	AuthorSuspended bool      `bson:"author_suspended,omitempty" json:"-"`
+	CreatedAt       time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+
+	// Embedded originals of reposts and quote posts, filled in per request
+	Reposted    *Post `bson:"-" json:"reposted_post,omitempty"`
+	Quoted      *Post `bson:"-" json:"quoted_post,omitempty"`
+	Unavailable bool  `bson:"-" json:"unavailable,omitempty"` // set on embedded posts that were deleted
+	Pinned      bool  `bson:"-" json:"pinned,omitempty"`      // set on the pinned posts of a profile
+
+	// Viewer-specific fields, filled in per request for signed-in callers
+	ViewerReactions []string `bson:"-" json:"viewer_reactions,omitempty"`
+	Bookmarked      *bool    `bson:"-" json:"bookmarked,omitempty"`
+
+	// Collapsed tells clients to hide the post behind its warning, following
+	// the caller's preferences
+	Collapsed bool `bson:"-" json:"collapsed,omitempty"`
+}
+
+// HasWarning reports whether the post is behind a content warning or marked sensitive
+func (p Post) HasWarning() bool {
+	return p.Warning != "" || p.Sensitive
+}
+
+// Post visibility levels. Posts without a visibility are public.
+const (
+	VisibilityPublic    = "public"
+	VisibilityFollowers = "followers" // the author's followers and mentioned users
+	VisibilityMentioned = "mentioned" // mentioned users only
+	VisibilityPrivate   = "private"   // the author only
+)
+
+// IsPublic reports whether anyone, signed in or not, may see the post
+func (p Post) IsPublic() bool {
+	return p.Visibility == "" || p.Visibility == VisibilityPublic
+}
+
+// Statuses of posts that have not been published yet
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled" // published by the scheduler at PublishAt
+)
+
+// IsPublished reports whether the post is live rather than a draft or scheduled post
+func (p Post) IsPublished() bool {
+	return p.Status == ""
+}
+
+// IsExpired reports whether an ephemeral post has passed its expiry time
+func (p Post) IsExpired() bool {
+	return p.ExpiresAt != nil && !time.Now().Before(*p.ExpiresAt)
+}
+
+// RenderContent renders Content to sanitized HTML, linking the resolved mentions
+func (p Post) RenderContent() string {
+	mentioned := make(map[string]string, len(p.Mentions))
+	for _, m := range p.Mentions {
+		mentioned[m.Username] = m.UserID.Hex()
+	}
+	return markdown.Render(p.Content, mentioned)
+}
+
+// Permalink returns the canonical URL of the post on the web frontend
+func (p Post) Permalink() string {
+	if p.ID.IsZero() {
+		return ""
+	}
+	return config.PublicURL() + "/posts/" + p.ID.Hex()
 }
 
+// MarshalJSON adds the permalink to every serialized post and fills in
+// defaults for posts stored before some fields existed
+func (p Post) MarshalJSON() ([]byte, error) {
+	// Posts from before visibility levels existed are public; placeholders have no visibility
+	if p.Visibility == "" && !p.CreatedAt.IsZero() {
+		p.Visibility = VisibilityPublic
+	}
+	// Posts from before Markdown rendering existed are rendered on the fly
+	if p.ContentHTML == "" && p.Content != "" {
+		p.ContentHTML = p.RenderContent()
+	}
+
+	type post Post
+	return json.Marshal(struct {
+		post
+		Permalink string `json:"permalink,omitempty"`
+	}{post(p), p.Permalink()})
+}
+
+// Mention is an @username in a post resolved to a user when the post was written.
+// Start and End are code point offsets of the token in Content, @ included.
+type Mention struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Username string             `bson:"username" json:"username"`
+	Start    int                `bson:"start" json:"start"`
+	End      int                `bson:"end" json:"end"`
+}
+
+// LinkPreview is the card shown for the first link in a post
+type LinkPreview struct {
+	URL         string `bson:"url" json:"url"`
+	Title       string `bson:"title" json:"title"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	ImageURL    string `bson:"image_url,omitempty" json:"image_url,omitempty"`
+	SiteName    string `bson:"site_name,omitempty" json:"site_name,omitempty"`
+}
+
+// PostAuthor is a copy of the author's profile kept on each post. It is
+// brought up to date in the background after the profile changes.
 type PostAuthor struct {
 	Name     string `bson:"name" json:"name"`
 	Username string `bson:"username" json:"username"`
+	Version  int    `bson:"version,omitempty" json:"-"` // the ProfileVersion copied
 }
 
+// MaxContentLength is the longest post content accepted, in characters. It
+// matches the max= bindings of the post inputs.
+const MaxContentLength = 5000
+
+// MaxWarningLength is the longest content warning accepted, in characters. It
+// matches the max= bindings of the warning inputs.
+const MaxWarningLength = 200
+
 type CreatePostInput struct {
-	Content string `json:"content" binding:"required"`
+	Content    string     `json:"content" binding:"required,max=5000"`
+	InReplyTo  string     `json:"in_reply_to"`
+	QuoteOf    string     `json:"quote_of"`
+	MediaIDs   []string   `json:"media_ids" binding:"omitempty,max=4"`
+	Poll       *PollInput `json:"poll"`
+	Visibility string     `json:"visibility" binding:"omitempty,oneof=public followers mentioned private"`
+	Warning    string     `json:"content_warning" binding:"omitempty,max=200"`
+	Sensitive  bool       `json:"sensitive"`
+
+	// Draft saves the post without publishing it; PublishAt schedules it instead
+	Draft     bool       `json:"draft"`
+	PublishAt *time.Time `json:"publish_at"`
+
+	// ExpiresAt makes the post ephemeral
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// SchedulePostInput reschedules an unpublished post. A null PublishAt turns it back into a draft.
+type SchedulePostInput struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// ContentWarningInput sets the warning shown in front of a post
+type ContentWarningInput struct {
+	Warning   string `json:"content_warning" binding:"omitempty,max=200"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+type UpdatePostInput struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// PostRevision is a previous version of a post's content, kept when the post is edited
+type PostRevision struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID     primitive.ObjectID `bson:"post_id" json:"post_id"`
+	Content    string             `bson:"content" json:"content"`
+	WrittenAt  time.Time          `bson:"written_at" json:"written_at"`
+	ReplacedAt time.Time          `bson:"replaced_at" json:"replaced_at"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reaction is one user's reaction of one type to a post
+type Reaction struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"post_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Type      string             `bson:"type" json:"type"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Tag tracks how often and how recently a hashtag was used, for autocomplete
+type Tag struct {
+	Name       string    `bson:"_id" json:"name"`
+	UseCount   int       `bson:"use_count" json:"use_count"`
+	LastUsedAt time.Time `bson:"last_used_at" json:"last_used_at"`
+}
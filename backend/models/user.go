@@ -8,13 +8,81 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Name      string             `bson:"name" json:"name"`
-	Username  string             `bson:"username" json:"username"`
-	Email     string             `bson:"email" json:"email"`
-	Password  string             `bson:"password" json:"-"` // "-" means this field won't be included in JSON responses
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID   `bson:"_id" json:"id"`
+	Name        string               `bson:"name" json:"name"`
+	Username    string               `bson:"username" json:"username"`
+	Email       string               `bson:"email" json:"email"`
+	Password    string               `bson:"password" json:"-"` // "-" means this field won't be included in JSON responses
+	Role        string               `bson:"role,omitempty" json:"role,omitempty"`
+	Suspension  *Suspension          `bson:"suspension,omitempty" json:"suspension,omitempty"`
+	PinnedPosts []primitive.ObjectID `bson:"pinned_posts,omitempty" json:"pinned_posts,omitempty"` // in display order
+	Preferences Preferences          `bson:"preferences" json:"preferences"`
+	// Counts of the follows collection, kept up to date as users follow and unfollow
+	FollowersCount int `bson:"followers_count" json:"followers_count"`
+	FollowingCount int `bson:"following_count" json:"following_count"`
+	// ProfileVersion grows with every change to the name or username, which
+	// are copied onto posts; AuthorSyncPending is set until all posts carry it
+	ProfileVersion    int  `bson:"profile_version,omitempty" json:"-"`
+	AuthorSyncPending bool `bson:"author_sync_pending,omitempty" json:"-"`
+	// PostsSuspended is set while the user's posts are flagged author_suspended
+	PostsSuspended bool      `bson:"posts_suspended,omitempty" json:"-"`
+	CreatedAt      time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Preferences control how posts are presented to the user
+type Preferences struct {
+	ExpandWarnings bool `bson:"expand_warnings" json:"expand_content_warnings"` // show posts with a content warning expanded
+	ShowSensitive  bool `bson:"show_sensitive" json:"show_sensitive"`           // show posts marked sensitive expanded
+}
+
+// Collapses reports whether a post should be shown collapsed under these preferences
+func (p Preferences) Collapses(post Post) bool {
+	return (post.Warning != "" && !p.ExpandWarnings) || (post.Sensitive && !p.ShowSensitive)
+}
+
+// User roles. An empty role is treated as RoleUser.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Suspension records why and until when an account is blocked.
+// A nil Until means the account is banned indefinitely.
+type Suspension struct {
+	Reason   string             `bson:"reason" json:"reason"`
+	Until    *time.Time         `bson:"until,omitempty" json:"until,omitempty"`
+	IssuedBy primitive.ObjectID `bson:"issued_by" json:"issued_by"`
+	IssuedAt time.Time          `bson:"issued_at" json:"issued_at"`
+}
+
+// Active reports whether the suspension is still in effect at the given time
+func (s *Suspension) Active(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	return s.Until == nil || s.Until.After(now)
+}
+
+// IsSuspended reports whether the user is currently suspended or banned
+func (u *User) IsSuspended() bool {
+	return u.Suspension.Active(time.Now())
+}
+
+// AsAuthor returns the profile data copied onto the user's posts
+func (u *User) AsAuthor() PostAuthor {
+	return PostAuthor{Name: u.Name, Username: u.Username, Version: u.ProfileVersion}
+}
+
+// IsModerator reports whether the user may moderate other users' content
+func (u *User) IsModerator() bool {
+	return IsModeratorRole(u.Role)
+}
+
+// IsModeratorRole reports whether the role grants moderation rights
+func IsModeratorRole(role string) bool {
+	return role == RoleModerator || role == RoleAdmin
 }
 
 // SignUpInput represents the data needed for user registration
@@ -38,6 +106,30 @@ type UpdateProfileInput struct {
 	Email    string `json:"email" binding:"omitempty,email"`
 }
 
+// PinPostInput places a pinned post among the others. Without a position the
+// post is pinned first.
+type PinPostInput struct {
+	Position *int `json:"position" binding:"omitempty,min=0"`
+}
+
+// ReorderPinsInput lists all of a user's pinned posts in their new order
+type ReorderPinsInput struct {
+	PostIDs []string `json:"post_ids" binding:"required,min=1"`
+}
+
+// UpdatePreferencesInput changes the given preferences and leaves the others as they are
+type UpdatePreferencesInput struct {
+	ExpandWarnings *bool `json:"expand_content_warnings"`
+	ShowSensitive  *bool `json:"show_sensitive"`
+}
+
+// SuspendUserInput represents the data needed to suspend or ban a user.
+// Omitting Until bans the account until it is explicitly unsuspended.
+type SuspendUserInput struct {
+	Reason string     `json:"reason" binding:"required" example:"Repeated harassment"`
+	Until  *time.Time `json:"until" example:"2024-12-31T00:00:00Z"`
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// snippetRadius is how many characters of context are kept around the first match
+const snippetRadius = 80
+
+// Snippet is an excerpt of a post around its first match. Matches are the
+// code point offsets into Text of the matched words and phrases, end exclusive,
+// like the offsets of mentions.
+type Snippet struct {
+	Text    string  `json:"text"`
+	Matches []Range `json:"matches"`
+}
+
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Highlight finds the words and phrases of q in content, matching whole words
+// case-insensitively, and returns the excerpt around the first one
+func Highlight(content string, q Query) Snippet {
+	text := []rune(content)
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []Range
+	for _, needle := range append(append([]string{}, q.Phrases...), q.Terms...) {
+		matches = append(matches, findWord(lower, []rune(strings.ToLower(needle)))...)
+	}
+	matches = merge(matches)
+
+	if len(matches) == 0 {
+		return excerpt(text, 0, min(len(text), 2*snippetRadius), nil)
+	}
+	start := max(0, matches[0].Start-snippetRadius)
+	end := min(len(text), matches[0].End+snippetRadius)
+	return excerpt(text, start, end, matches)
+}
+
+// findWord returns every occurrence of needle in text that starts and ends on a word boundary
+func findWord(text, needle []rune) []Range {
+	var found []Range
+	if len(needle) == 0 {
+		return nil
+	}
+	for i := 0; i+len(needle) <= len(text); i++ {
+		if !equalRunes(text[i:i+len(needle)], needle) {
+			continue
+		}
+		end := i + len(needle)
+		if (i > 0 && isWordRune(text[i-1])) || (end < len(text) && isWordRune(text[end])) {
+			continue
+		}
+		found = append(found, Range{Start: i, End: end})
+		i = end - 1
+	}
+	return found
+}
+
+// merge sorts ranges and joins the ones that overlap
+func merge(ranges []Range) []Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	var merged []Range
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// excerpt cuts text[start:end], marking cuts with an ellipsis, and shifts the
+// matches that fall inside it
+func excerpt(text []rune, start, end int, matches []Range) Snippet {
+	var out []rune
+	shift := -start
+	if start > 0 {
+		out = append(out, '…')
+		shift++
+	}
+	out = append(out, text[start:end]...)
+	if end < len(text) {
+		out = append(out, '…')
+	}
+
+	snippet := Snippet{Text: string(out), Matches: []Range{}}
+	for _, m := range matches {
+		if m.Start >= start && m.End <= end {
+			snippet.Matches = append(snippet.Matches, Range{Start: m.Start + shift, End: m.End + shift})
+		}
+	}
+	return snippet
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoEngine searches the text index on posts.content
+type MongoEngine struct {
+	db *mongo.Database
+}
+
+func NewMongoEngine(db *mongo.Database) *MongoEngine {
+	return &MongoEngine{db: db}
+}
+
+func (e *MongoEngine) Search(ctx context.Context, q Query) ([]Hit, error) {
+	// Only live, unexpired, published posts with content of their own are candidates
+	filter := bson.M{
+		"$text":      bson.M{"$search": textSearch(q)},
+		"deleted_at": nil,
+		"expires_at": bson.M{"$not": bson.M{"$lte": time.Now()}},
+		"status":     nil,
+		"repost_of":  nil,
+	}
+	if q.AuthorID != nil {
+		filter["user_id"] = *q.AuthorID
+	}
+	if q.Since != nil || q.Until != nil {
+		created := bson.M{}
+		if q.Since != nil {
+			created["$gte"] = *q.Since
+		}
+		if q.Until != nil {
+			created["$lt"] = *q.Until
+		}
+		filter["created_at"] = created
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	sort := bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	if q.Sort == SortRecent {
+		sort = bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, "score": score}).
+		SetSort(sort).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := e.db.Collection("posts").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Score float64            `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(docs))
+	for _, d := range docs {
+		hits = append(hits, Hit{PostID: d.ID, Score: d.Score})
+	}
+	return hits, nil
+}
+
+// textSearch builds a $text search string. Mongo ORs plain words, requires
+// every quoted phrase and drops documents with a -word.
+func textSearch(q Query) string {
+	var parts []string
+	for _, t := range q.Terms {
+		parts = append(parts, strings.TrimLeft(strings.ReplaceAll(t, `"`, ""), "-"))
+	}
+	for _, p := range q.Phrases {
+		parts = append(parts, `"`+strings.ReplaceAll(p, `"`, "")+`"`)
+	}
+	for _, x := range q.Excluded {
+		parts = append(parts, "-"+strings.ReplaceAll(x, `"`, ""))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sort orders of search results
+const (
+	SortRelevance = "relevance"
+	SortRecent    = "recent"
+)
+
+// Engine finds posts matching a query. Engines only rank candidates; callers
+// load the posts themselves and apply visibility rules, so an engine never
+// decides who may see what and can be swapped without touching those rules.
+type Engine interface {
+	Search(ctx context.Context, q Query) ([]Hit, error)
+}
+
+// Query is a parsed search request. Offset and Limit page through the hits.
+type Query struct {
+	Terms    []string // any of these words
+	Phrases  []string // each of these exact phrases
+	Excluded []string // none of these words
+	AuthorID *primitive.ObjectID
+	Since    *time.Time
+	Until    *time.Time
+	Sort     string
+	Offset   int
+	Limit    int
+}
+
+// Hit is a matching post with its relevance score; higher is better
+type Hit struct {
+	PostID primitive.ObjectID
+	Score  float64
+}
+
+// ParseQuery splits user input into words, "quoted phrases" and -excluded
+// words. An unterminated quote runs to the end of the input.
+func ParseQuery(input string) Query {
+	var q Query
+	for input != "" {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			break
+		}
+
+		if input[0] == '"' {
+			phrase, rest, _ := strings.Cut(input[1:], `"`)
+			if phrase = strings.Join(strings.Fields(phrase), " "); phrase != "" {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			input = rest
+			continue
+		}
+
+		word, rest, _ := strings.Cut(input, " ")
+		word = strings.Trim(word, `"`)
+		switch {
+		case strings.HasPrefix(word, "-") && len(word) > 1:
+			q.Excluded = append(q.Excluded, word[1:])
+		case word != "" && word != "-":
+			q.Terms = append(q.Terms, word)
+		}
+		input = rest
+	}
+	return q
+}
+
+// Empty reports whether the query has nothing to match on
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
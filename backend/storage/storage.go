@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"unleashed-space/config"
+)
+
+// ErrNotFound is returned when a key does not exist in the store
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage keeps uploaded media files under opaque keys
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New builds the storage backend selected in cfg
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocal(cfg.Dir)
+	case "s3":
+		return NewS3(cfg.Endpoint, cfg.Bucket, cfg.Region, cfg.AccessKey, cfg.SecretKey)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}